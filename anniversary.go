@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandSetAnniversary = "set anniversary"
+
+	// anniversaryKeySuffix separates anniversaries from birthdays in the cache
+	anniversaryKeySuffix = "/anniversary"
+)
+
+func (k eventKind) String() string {
+	switch k {
+	case eventAnniversary:
+		return "anniversary"
+	case eventCustom:
+		return "event"
+	default:
+		return "birthday"
+	}
+}
+
+// cacheKey returns the key of the event in the cache buckets
+func (info bdInfo) cacheKey(id string) string {
+	switch info.Kind {
+	case eventAnniversary:
+		return id + anniversaryKeySuffix
+	case eventCustom:
+		return eventKeyPrefix + id
+	default:
+		return id
+	}
+}
+
+// honorees returns the user IDs of the people celebrated by the event
+func (info bdInfo) honorees(id string) []string {
+	if info.Kind == eventCustom {
+		return info.Honorees
+	}
+	return []string{id}
+}
+
+// initAnniversaries finds the start date profile field and loads the start date roster, if they're configured
+func initAnniversaries(sc slack.Client, c *config, now time.Time) error {
+	if !c.Anniversaries {
+		return nil
+	}
+
+	for _, name := range c.AnnivSourceNames {
+		var err error
+		switch strings.ToLower(name) {
+		case sourceProfile:
+			if c.AnnivFieldID, err = findProfileField(sc, c.AnnivField); err == nil && c.AnnivFieldID == "" {
+				err = errors.New("start date profile field is not set")
+			}
+		case sourceRoster:
+			c.AnnivRoster, err = loadStartDates(c.AnnivRosterFile, c.DateOrder, now)
+		default:
+			err = errors.Errorf("unknown start date source %q", name)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to init start date source %s", name)
+		}
+	}
+	return nil
+}
+
+// parseStartDate parses the work start date in any of the supported formats and returns it as YYYY-MM-DD.
+// Unlike birthdays, start dates must have the year.
+func parseStartDate(s string, order dateOrder, now time.Time) (string, error) {
+	d, err := parseBirthday(s, order, now)
+	if err != nil {
+		return "", err
+	}
+	if d.Year == 0 {
+		return "", errors.New("year of the start date is required")
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day), nil
+}
+
+// loadStartDates reads the start date roster, which has the same format as the birthday one
+func loadStartDates(path string, order dateOrder, now time.Time) (map[string]string, error) {
+	if path == "" {
+		return nil, errors.New("start date roster file is not set")
+	}
+
+	rows, err := readRoster(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]string, len(rows))
+	for _, row := range rows {
+		date, err := parseStartDate(row[1], order, now)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping start date roster record for user %s", row[0])
+			continue
+		}
+		dates[row[0]] = date
+	}
+	logrus.Infof("Loaded %d start dates from roster %s", len(dates), path)
+	return dates, nil
+}
+
+// userStartDate returns the user's work start date in the YYYY-MM-DD format from the first source which has it.
+// Start date set by the user always takes precedence.
+func userStartDate(now time.Time, p *slack.UserProfile, db *DB, c *config) string {
+	date, err := db.GetUserStartDate(p.ID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get start date of user %s", p.ID)
+	}
+	if date != "" {
+		return date
+	}
+
+	for _, name := range c.AnnivSourceNames {
+		switch strings.ToLower(name) {
+		case sourceProfile:
+			raw := strings.TrimSpace(p.Fields[c.AnnivFieldID].Value)
+			if raw == "" {
+				continue
+			}
+			if date, err = parseStartDate(raw, c.DateOrder, now); err != nil {
+				logrus.WithError(err).Debugf("Unable to parse start date of user %s", p.ID)
+				continue
+			}
+			return date
+		case sourceRoster:
+			if date = c.AnnivRoster[p.ID]; date != "" {
+				return date
+			}
+		}
+	}
+	return ""
+}
+
+// planAnniversary decides if the user's work anniversary should be announced to the manager or get its channel
+func planAnniversary(now time.Time, p *slack.UserProfile, db *DB, c *config, plan *announcePlan) {
+	start, err := time.Parse("2006-01-02", userStartDate(now, p, db, c))
+	if err != nil {
+		// no start date, nothing to celebrate
+		return
+	}
+
+	userAnniv := fmt.Sprintf("%02d%02d", start.Day(), int(start.Month()))
+	days, date, err := getUserBDInfo(now, userAnniv, c.LeapDay)
+	if err != nil {
+		logrus.Debug(err)
+		return
+	}
+
+	years := date.Year() - start.Year()
+	if years < 1 {
+		return
+	}
+
+	info := bdInfo{p.RealName, strings.ToLower(p.LastName), userAnniv + strconv.Itoa(date.Year()), days, date, eventAnniversary, years, nil}
+
+	var (
+		bucket []byte
+		target map[string]bdInfo
+	)
+	switch window := announceWindow(now, date, c); {
+	case window <= c.AnnivHighTreshold && window > c.AnnivLowTreshold:
+		bucket, target = db.ManagerBucketName, plan.AnnivManager
+	case window <= c.AnnivLowTreshold:
+		bucket, target = db.ChannelBucketName, plan.AnnivChannel
+	default:
+		return
+	}
+
+	ok, err := db.CheckUserBDInCache(bucket, info.cacheKey(p.ID), info.Birthday)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to check user %s in cache", p.ID)
+	} else if ok {
+		logrus.Infof("Anniversary of user %s is present in cache, skipping", p.ID)
+		return
+	}
+
+	logrus.Infof("Planning %d years anniversary of user %s (%d day(s) left)", years, p.ID, days)
+	target[p.ID] = info
+}
+
+// handleSetAnniversary saves the work start date set by the user
+func handleSetAnniversary(rtm slack.RTM, clk clock, db *DB, c *config, msgs *messages, m slack.Message, args string) {
+	date, err := parseStartDate(args, c.DateOrder, clk.Now().In(c.Location))
+	if err != nil {
+		logrus.WithError(err).Infof("Unable to parse start date of user %s", m.User)
+		sendReply(rtm, m, bdParseErrorText(msgs, m.User, err))
+		return
+	}
+
+	if err = db.SaveUserStartDate(m.User, date); err != nil {
+		logrus.WithError(err).Errorf("Unable to save start date of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+
+	// setting the start date cancels the opt-out
+	if err = optIn(db, m.User); err != nil {
+		logrus.WithError(err).Errorf("Unable to remove opt-out of user %s", m.User)
+	}
+
+	logrus.Infof("User %s has set the start date", m.User)
+	sendReply(rtm, m, fmt.Sprintf(msgs.AnnivSaved, m.User))
+}
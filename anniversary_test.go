@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestUserStartDate(t *testing.T) {
+	sc, db, c, _ := newTestBot(t)
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	sc.TeamProfileFields = []slack.TeamProfileField{{ID: "Xf01", Label: "Start date"}}
+	c.Anniversaries, c.AnnivField = true, "start date"
+	c.AnnivRosterFile = writeTestFile(t, "start.csv", "U1,2010-01-01\nU2,2011-02-02\nU3,03.03\n")
+	if err := db.SaveUserStartDate("U1", "2012-03-03"); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := func(id, start string) *slack.UserProfile {
+		return &slack.UserProfile{ID: id, Fields: slack.ProfileFields{"Xf01": {Value: start}}}
+	}
+	tests := []struct {
+		sources []string
+		p       *slack.UserProfile
+		want    string
+	}{
+		// set by the user
+		{[]string{"profile", "roster"}, profile("U1", "05.05.2015"), "2012-03-03"},
+		{[]string{"profile", "roster"}, profile("U2", "05.05.2015"), "2015-05-05"},
+		{[]string{"roster", "profile"}, profile("U2", "05.05.2015"), "2011-02-02"},
+		// profile date without the year is skipped
+		{[]string{"profile", "roster"}, profile("U2", "05.05"), "2011-02-02"},
+		// roster date without the year is skipped as well
+		{[]string{"roster"}, profile("U3", ""), ""},
+		{[]string{"roster", "profile"}, profile("U4", "2016-06-06"), "2016-06-06"},
+		{[]string{"roster"}, profile("U4", "2016-06-06"), ""},
+	}
+	for _, tt := range tests {
+		c.AnnivSourceNames = tt.sources
+		if err := initAnniversaries(sc, c, now); err != nil {
+			t.Fatal(err)
+		}
+		if got := userStartDate(now, tt.p, db, c); got != tt.want {
+			t.Errorf("sources %v, user %s: expected %q, got %q", tt.sources, tt.p.ID, tt.want, got)
+		}
+	}
+
+	c.AnnivSourceNames = []string{"hr"}
+	if err := initAnniversaries(sc, c, now); err == nil {
+		t.Error("expected an error for the unknown source")
+	}
+	c.AnnivSourceNames, c.AnnivField = []string{"profile"}, "Hire date"
+	if err := initAnniversaries(sc, c, now); err == nil {
+		t.Error("expected an error for the missing profile field")
+	}
+}
+
+func TestPlanAnniversary(t *testing.T) {
+	_, db, c, _ := newTestBot(t)
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	c.Anniversaries, c.AnnivSourceNames = true, []string{"roster"}
+	c.AnnivHighTreshold, c.AnnivLowTreshold = 14, 5
+	c.AnnivRoster = map[string]string{
+		"U1": "2015-03-10", // 9 days left, beyond the birthday tresholds
+		"U2": "2018-03-04", // 3 days left
+		"U3": "2019-03-04", // less than a year
+		"U4": "2010-03-20", // 19 days left
+		"U5": "2016-03-05", // already announced
+	}
+	if err := db.SaveUserBDToCache(db.ChannelBucketName, "U5"+anniversaryKeySuffix, "05032019"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &announcePlan{AnnivManager: make(map[string]bdInfo), AnnivChannel: make(map[string]bdInfo)}
+	for _, id := range []string{"U1", "U2", "U3", "U4", "U5"} {
+		planAnniversary(now, &slack.UserProfile{ID: id}, db, c, plan)
+	}
+
+	if len(plan.AnnivManager) != 1 || plan.AnnivManager["U1"].Years != 4 || plan.AnnivManager["U1"].DaysLeft != 9 {
+		t.Errorf("expected 4 years anniversary of U1 for the manager, got %+v", plan.AnnivManager)
+	}
+	if len(plan.AnnivChannel) != 1 || plan.AnnivChannel["U2"].Years != 1 || plan.AnnivChannel["U2"].Birthday != "04032019" {
+		t.Errorf("expected 1 year anniversary of U2 for the channel, got %+v", plan.AnnivChannel)
+	}
+	if info := plan.AnnivChannel["U2"]; info.cacheKey("U2") != "U2"+anniversaryKeySuffix {
+		t.Errorf("expected anniversary cache key, got %q", info.cacheKey("U2"))
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dateOrder describes how the ambiguous numeric dates like 03/04 are parsed
+type dateOrder int
+
+// date orders
+const (
+	dayFirst dateOrder = iota
+	monthFirst
+)
+
+// date order names, used in config
+const (
+	dateOrderDMY = "dmy"
+	dateOrderMDY = "mdy"
+)
+
+var (
+	isoDateRe     = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	dashDateRe    = regexp.MustCompile(`^(\d{1,2})-(\d{1,2})$`)
+	numericDateRe = regexp.MustCompile(`^(\d{1,2})[./](\d{1,2})(?:[./](\d{2}|\d{4}))?$`)
+	compactDateRe = regexp.MustCompile(`^(\d{2})(\d{2})$`)
+	ordinalRe     = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th|-?го|-?е)?$`)
+	yearRe        = regexp.MustCompile(`^\d{4}$`)
+)
+
+// monthNames contains full month names (and their grammatical forms) in the supported languages.
+// Any unambiguous prefix of at least 3 letters is accepted as well.
+var monthNames = map[time.Month][]string{
+	time.January:   {"january", "январь", "января"},
+	time.February:  {"february", "февраль", "февраля"},
+	time.March:     {"march", "март", "марта"},
+	time.April:     {"april", "апрель", "апреля"},
+	time.May:       {"may", "май", "мая"},
+	time.June:      {"june", "июнь", "июня"},
+	time.July:      {"july", "июль", "июля"},
+	time.August:    {"august", "август", "августа"},
+	time.September: {"september", "сентябрь", "сентября"},
+	time.October:   {"october", "октябрь", "октября"},
+	time.November:  {"november", "ноябрь", "ноября"},
+	time.December:  {"december", "декабрь", "декабря"},
+}
+
+// bdDate describes the parsed birthday
+type bdDate struct {
+	Day   int
+	Month time.Month
+	Year  int // zero if unknown
+}
+
+// DDMM returns the birthday in the DDMM format, used for caching
+func (d bdDate) DDMM() string {
+	return fmt.Sprintf("%02d%02d", d.Day, int(d.Month))
+}
+
+// parseDateOrder parses the date order config value
+func parseDateOrder(s string) (dateOrder, error) {
+	switch strings.ToLower(s) {
+	case "", dateOrderDMY:
+		return dayFirst, nil
+	case dateOrderMDY:
+		return monthFirst, nil
+	default:
+		return dayFirst, errors.Errorf("unknown date order %q, expected %q or %q", s, dateOrderDMY, dateOrderMDY)
+	}
+}
+
+// parseBirthday parses the birthday in one of the supported formats:
+// DDMM, DD.MM, DD/MM, MM-DD, YYYY-MM-DD, DD.MM.YYYY, '24 March', 'March 24th', '24 марта' and so on.
+// Ambiguous numeric dates are parsed according to the date order, years are checked against now.
+// Error message is meant to be shown to the user.
+func parseBirthday(s string, order dateOrder, now time.Time) (bdDate, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return bdDate{}, errors.New("birthday is empty")
+	}
+
+	var (
+		d   bdDate
+		err error
+	)
+	switch {
+	case isoDateRe.MatchString(s):
+		parts := isoDateRe.FindStringSubmatch(s)
+		d.Year, _ = strconv.Atoi(parts[1])
+		d, err = numericDate(parts[3], parts[2], d.Year)
+	case dashDateRe.MatchString(s):
+		parts := dashDateRe.FindStringSubmatch(s)
+		d, err = orderedDate(parts[1], parts[2], monthFirst, 0)
+	case numericDateRe.MatchString(s):
+		parts := numericDateRe.FindStringSubmatch(s)
+		year := 0
+		if parts[3] != "" {
+			if year, err = parseYear(parts[3], now); err != nil {
+				return bdDate{}, err
+			}
+		}
+		d, err = orderedDate(parts[1], parts[2], order, year)
+	case compactDateRe.MatchString(s):
+		// legacy format, always DDMM
+		parts := compactDateRe.FindStringSubmatch(s)
+		d, err = numericDate(parts[1], parts[2], 0)
+	default:
+		d, err = parseWordDate(s)
+	}
+	if err != nil {
+		return bdDate{}, err
+	}
+
+	if err = validateBDDate(d, now); err != nil {
+		return bdDate{}, err
+	}
+	return d, nil
+}
+
+// parseWordDate parses dates with month names, like '24 March 1990' or 'March 24th, 1990'
+func parseWordDate(s string) (bdDate, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '.' || r == '/'
+	})
+
+	var (
+		d              bdDate
+		dayOK, monthOK bool
+	)
+	for _, f := range fields {
+		switch {
+		case yearRe.MatchString(f) && d.Year == 0:
+			d.Year, _ = strconv.Atoi(f)
+		case ordinalRe.MatchString(f) && !dayOK:
+			d.Day, _ = strconv.Atoi(ordinalRe.FindStringSubmatch(f)[1])
+			dayOK = true
+		case !monthOK:
+			month, ok := parseMonthName(f)
+			if !ok {
+				return bdDate{}, errors.Errorf("unknown month %q", f)
+			}
+			d.Month, monthOK = month, true
+		default:
+			return bdDate{}, errors.Errorf("unexpected %q in the date", f)
+		}
+	}
+
+	if !dayOK || !monthOK {
+		return bdDate{}, errors.Errorf("%q is not a date, try something like 24.03 or 24 March", s)
+	}
+	return d, nil
+}
+
+func parseMonthName(s string) (time.Month, bool) {
+	if len([]rune(s)) < 3 {
+		return 0, false
+	}
+
+	var (
+		result time.Month
+		found  bool
+	)
+	for month, names := range monthNames {
+		for _, name := range names {
+			if strings.HasPrefix(name, s) {
+				if found && result != month {
+					return 0, false // ambiguous
+				}
+				result, found = month, true
+			}
+		}
+	}
+	return result, found
+}
+
+// orderedDate parses the numeric date parts according to the order.
+// If one of the parts can't be a month, the order is deduced.
+func orderedDate(first, second string, order dateOrder, year int) (bdDate, error) {
+	a, _ := strconv.Atoi(first)
+	b, _ := strconv.Atoi(second)
+	switch {
+	case a > 12 && b <= 12:
+		order = dayFirst
+	case b > 12 && a <= 12:
+		order = monthFirst
+	}
+
+	if order == monthFirst {
+		return numericDate(second, first, year)
+	}
+	return numericDate(first, second, year)
+}
+
+func numericDate(day, month string, year int) (bdDate, error) {
+	d, _ := strconv.Atoi(day)
+	m, _ := strconv.Atoi(month)
+	if m < 1 || m > 12 {
+		return bdDate{}, errors.Errorf("month %d is out of range", m)
+	}
+	return bdDate{Day: d, Month: time.Month(m), Year: year}, nil
+}
+
+func parseYear(s string, now time.Time) (int, error) {
+	year, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("year %q is not a number", s)
+	}
+
+	// two-digit years are either from this century or from the previous one
+	if len(s) == 2 {
+		if year > now.Year()%100 {
+			year += 1900
+		} else {
+			year += 2000
+		}
+	}
+	return year, nil
+}
+
+func validateBDDate(d bdDate, now time.Time) error {
+	if d.Month < time.January || d.Month > time.December {
+		return errors.Errorf("month %d is out of range", d.Month)
+	}
+
+	// use leap year to allow 29th of February if the year is unknown
+	year := d.Year
+	if year == 0 {
+		year = 2000
+	} else if year < 1900 || year > now.Year() {
+		return errors.Errorf("year %d is out of range", year)
+	}
+
+	if d.Day < 1 || time.Date(year, d.Month, d.Day, 0, 0, 0, 0, time.UTC).Day() != d.Day {
+		if d.Year == 0 {
+			return errors.Errorf("day %d is out of range for %s", d.Day, d.Month)
+		}
+		return errors.Errorf("day %d is out of range for %s %d", d.Day, d.Month, d.Year)
+	}
+	return nil
+}
+
+// bdParseErrorText formats the birthday parse error message.
+// If the message has a second verb, error reason is put there.
+func bdParseErrorText(msgs *messages, userID string, err error) string {
+	if strings.Count(msgs.BDParseError, "%s") > 1 {
+		return fmt.Sprintf(msgs.BDParseError, userID, err)
+	}
+	return fmt.Sprintf(msgs.BDParseError, userID)
+}
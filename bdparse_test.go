@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBirthdayYearAgainstNow(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in       string
+		wantYear int
+		wantErr  bool
+	}{
+		{"24.03.2019", 2019, false},
+		{"24.03.2020", 0, true},
+		{"24.03.19", 2019, false},
+		{"24.03.20", 1920, false},
+		{"24.03.1899", 0, true},
+		{"29.02.2016", 2016, false},
+		{"29.02.2019", 0, true},
+	}
+	for _, tt := range tests {
+		d, err := parseBirthday(tt.in, dayFirst, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBirthday(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if d.Year != tt.wantYear {
+			t.Errorf("parseBirthday(%q): expected year %d, got %d", tt.in, tt.wantYear, d.Year)
+		}
+	}
+}
+
+func TestParseBirthday(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		order   dateOrder
+		want    bdDate
+		wantErr bool
+	}{
+		{"2403", dayFirst, bdDate{24, time.March, 0}, false},
+		{"0324", monthFirst, bdDate{}, true}, // legacy format is always DDMM
+		{"24.03", dayFirst, bdDate{24, time.March, 0}, false},
+		{"24/03", dayFirst, bdDate{24, time.March, 0}, false},
+		{"03-24", dayFirst, bdDate{24, time.March, 0}, false},
+		{"1990-03-24", monthFirst, bdDate{24, time.March, 1990}, false},
+		{"24.03.1990", dayFirst, bdDate{24, time.March, 1990}, false},
+		{"24.03.90", dayFirst, bdDate{24, time.March, 1990}, false},
+		{"03/04", dayFirst, bdDate{3, time.April, 0}, false},
+		{"03/04", monthFirst, bdDate{4, time.March, 0}, false},
+		{"24/03", monthFirst, bdDate{24, time.March, 0}, false}, // 24 can't be a month
+		{"24 March", dayFirst, bdDate{24, time.March, 0}, false},
+		{"March 24th, 1990", dayFirst, bdDate{24, time.March, 1990}, false},
+		{" 1st of may ", dayFirst, bdDate{}, true},
+		{"1st May", dayFirst, bdDate{1, time.May, 0}, false},
+		{"24 марта", dayFirst, bdDate{24, time.March, 0}, false},
+		{"2-го марта", dayFirst, bdDate{2, time.March, 0}, false},
+		{"24 мар", dayFirst, bdDate{24, time.March, 0}, false},
+		{"24 mar", dayFirst, bdDate{24, time.March, 0}, false},
+		{"24 ma", dayFirst, bdDate{}, true}, // too short
+		{"24 ju", dayFirst, bdDate{}, true}, // too short and ambiguous
+		{"24 ма", dayFirst, bdDate{}, true}, // too short
+		{"29.02", dayFirst, bdDate{29, time.February, 0}, false},
+		{"29.02.2016", dayFirst, bdDate{29, time.February, 2016}, false},
+		{"29.02.1991", dayFirst, bdDate{}, true},
+		{"31.04", dayFirst, bdDate{}, true},
+		{"00.04", dayFirst, bdDate{}, true},
+		{"13/13", dayFirst, bdDate{}, true},
+		{"", dayFirst, bdDate{}, true},
+		{"tomorrow", dayFirst, bdDate{}, true},
+	}
+	for _, tt := range tests {
+		d, err := parseBirthday(tt.in, tt.order, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBirthday(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if d != tt.want {
+			t.Errorf("parseBirthday(%q): expected %+v, got %+v", tt.in, tt.want, d)
+		}
+	}
+}
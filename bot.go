@@ -9,11 +9,9 @@ import (
 	"sync"
 	"time"
 
-	goage "github.com/bearbin/go-age"
 	"github.com/nezorflame/bd-reminder-bot/slack"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	ws "golang.org/x/net/websocket"
 )
 
 const (
@@ -24,7 +22,7 @@ const (
 	errorMsgNameTaken = "API error: name_taken"
 )
 
-func msgWatcher(ctx context.Context, conn *ws.Conn, c *config, msgs *messages) error {
+func msgWatcher(ctx context.Context, rtm slack.RTM, sc slack.Client, clk clock, db *DB, c *config, msgs *messages) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -32,7 +30,7 @@ func msgWatcher(ctx context.Context, conn *ws.Conn, c *config, msgs *messages) e
 			return nil
 		default:
 			// read each incoming message
-			m, err := slack.GetWSMessage(conn)
+			m, err := rtm.GetMessage()
 			if err != nil {
 				// ignore timeout errors, log others
 				if netErr, ok := err.(net.Error); ok {
@@ -60,26 +58,32 @@ func msgWatcher(ctx context.Context, conn *ws.Conn, c *config, msgs *messages) e
 				case commandHi:
 					go func(m slack.Message) {
 						m.Text = "<@" + m.User + "> hello!"
-						if err := slack.SendWSMessage(conn, m); err != nil {
+						if err := rtm.SendMessage(m); err != nil {
 							logrus.WithError(err).Errorln("Unable to send message to Slack")
 						}
 					}(m)
 				case commandBirthday:
 					go func(m slack.Message) {
-						user, err := slack.GetUserProfile(c.LegacyToken, m.User)
+						user, err := sc.GetUserProfile(m.User)
 						if err != nil {
 							logrus.WithError(err).Error("Unable to get user profile")
 							m.Text = fmt.Sprintf(msgs.ProfileError, m.User)
-							if err := slack.SendWSMessage(conn, m); err != nil {
+							if err := rtm.SendMessage(m); err != nil {
 								logrus.WithError(err).Errorln("Unable to send message to Slack")
 							}
 							return
 						}
 
-						days, err := getUserBDInfo(time.Now().In(c.Location), user.Skype)
+						// days are counted in the user's own time zone
+						var days int
+						now := clk.Now().In(userLocation(sc, c, user.ID))
+						bd, err := parseBirthday(userBirthday(user, c), c.DateOrder, now)
+						if err == nil {
+							days, _, err = getUserBDInfo(now, bd.DDMM(), c.LeapDay)
+						}
 						if err != nil {
 							logrus.WithError(err).Error("Unable to get user BD info")
-							m.Text = fmt.Sprintf(msgs.BDParseError, user.ID)
+							m.Text = bdParseErrorText(msgs, user.ID, err)
 						} else if days > 0 {
 							logrus.Infof("User %s: %d days left", user.ID, days)
 							m.Text = fmt.Sprintf(msgs.PersonalIncoming, user.ID, days)
@@ -87,26 +91,76 @@ func msgWatcher(ctx context.Context, conn *ws.Conn, c *config, msgs *messages) e
 							logrus.Infof("User %s: birthday is today", user.ID)
 							m.Text = fmt.Sprintf(msgs.PersonalToday, user.ID)
 						}
-						if err := slack.SendWSMessage(conn, m); err != nil {
+						if err := rtm.SendMessage(m); err != nil {
 							logrus.WithError(err).Errorln("Unable to send message to Slack")
 						}
 					}(m)
 				case commandShutdown:
 					if m.User != c.ManagerID {
 						m.Text = msgs.ShutdownError
-						if err := slack.SendWSMessage(conn, m); err != nil {
+						if err := rtm.SendMessage(m); err != nil {
 							logrus.WithError(err).Errorln("Unable to send message to Slack")
 						}
 						continue
 					}
 					if msgs.ShutdownAnnounce != "" {
 						m.Text = msgs.ShutdownAnnounce
-						if err := slack.SendWSMessage(conn, m); err != nil {
+						if err := rtm.SendMessage(m); err != nil {
 							logrus.WithError(err).Errorln("Unable to send message to Slack")
 						}
 					}
 					return nil
+				case commandForgetMe, commandForgetBirthday:
+					go handleForgetBirthday(rtm, db, msgs, m, strings.ToLower(mText) == commandForgetMe)
 				default:
+					if args, ok := commandArgs(mText, commandSetBirthday); ok {
+						go handleSetBirthday(rtm, clk, db, c, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandUpcoming); ok {
+						go handleUpcoming(rtm, sc, clk, db, c, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandEvent); ok {
+						go handleEventCommand(rtm, db, c, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandWish); ok {
+						go handleWishCommand(rtm, db, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandIdea); ok {
+						go handleIdea(rtm, db, msgs, m, args)
+						continue
+					}
+					if strings.ToLower(mText) == commandIdeas {
+						go handleIdeas(rtm, db, msgs, m)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandVote); ok {
+						go handleVote(rtm, db, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandPaid); ok {
+						go handlePaid(rtm, db, msgs, m, args)
+						continue
+					}
+					if strings.ToLower(mText) == commandSkip {
+						go handleSkip(rtm, db, msgs, m)
+						continue
+					}
+					if strings.ToLower(mText) == commandStatus {
+						go handleStatus(rtm, db, msgs, m)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandDigest); ok {
+						go handleDigestCommand(rtm, db, c, msgs, m, args)
+						continue
+					}
+					if args, ok := commandArgs(mText, commandSetAnniversary); ok && c.Anniversaries {
+						go handleSetAnniversary(rtm, clk, db, c, msgs, m, args)
+						continue
+					}
 					// ignore this
 					continue
 				}
@@ -115,116 +169,187 @@ func msgWatcher(ctx context.Context, conn *ws.Conn, c *config, msgs *messages) e
 	}
 }
 
-func bdWatcher(ctx context.Context, db *DB, c *config, m *messages) error {
+func bdWatcher(ctx context.Context, sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
 	// first start
-	mgrDM, err := slack.FindDMByUserID(c.LegacyToken, c.ManagerID)
-	if err != nil {
-		return errors.Wrap(err, "unable to find manager's DM")
+	if err := findManagerDM(sc, c); err != nil {
+		return err
 	}
-	c.ManagerDM = mgrDM
 
-	now := time.Now().In(c.Location)
-	logrus.Infoln("Starting first birthday check at", now.Format(time.RFC1123))
-	if now.Hour() >= c.WorkdayStart && now.Hour() <= c.WorkdayEnd {
-		if err := announceBirthdays(db, c, m); err != nil {
-			return errors.Wrap(err, "unable to print birthdays")
-		}
-	} else {
-		logrus.Info("Too soon, skipping first check")
+	// replay the checks missed while the bot was down
+	if err := catchUp(sc, clk, db, c, m); err != nil {
+		return errors.Wrap(err, "unable to catch up")
 	}
 
-	// run the watcher on hourly basis
-	ticker := time.NewTicker(time.Hour)
+	// run the checks according to the schedules
 	for {
+		now := clk.Now().In(c.Location)
+		nextManager := c.Calendar.NextWorkingTime(c.ManagerSchedule, now)
+		nextChannel := c.Calendar.NextWorkingTime(c.ChannelSchedule, now)
+		if nextManager.IsZero() && nextChannel.IsZero() {
+			return errors.New("schedules never fire")
+		}
+
+		next, kinds := nextManager, announceManager
+		switch {
+		case nextManager.IsZero() || !nextChannel.IsZero() && nextChannel.Before(nextManager):
+			next, kinds = nextChannel, announceChannels
+		case nextChannel.Equal(nextManager):
+			kinds |= announceChannels
+		}
+		logrus.Infoln("Next birthday check is at", next.Format(time.RFC1123))
+		timer := time.NewTimer(next.Sub(now))
+
 		select {
 		case <-ctx.Done():
 			logrus.Warn("Stopping birthday watcher")
-			ticker.Stop()
+			timer.Stop()
 			return nil
-		case t := <-ticker.C:
-			// check if we can send it now
-			t = t.In(c.Location)
-			logrus.Infoln("Starting new birthday check at", t.Format(time.RFC1123))
-			if t.Hour() < c.WorkdayStart || t.Hour() > c.WorkdayEnd {
-				logrus.Info("Too soon to check, skipping")
-				continue
-			}
-
-			if err := announceBirthdays(db, c, m); err != nil {
-				ticker.Stop()
+		case <-timer.C:
+			logrus.Infoln("Starting new birthday check at", clk.Now().In(c.Location).Format(time.RFC1123))
+			if err := announceBirthdays(sc, clk, db, c, m, kinds, time.Time{}); err != nil {
 				return errors.Wrap(err, "unable to print birthdays")
 			}
+			if err := db.SaveLastCheck(clk.Now()); err != nil {
+				logrus.WithError(err).Errorln("Unable to save last check time")
+			}
+			if err := postMonthlyDigest(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to post monthly digest")
+			}
+			if err := closeCollections(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to close collections")
+			}
+			if err := remindContributors(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to remind contributors")
+			}
+			if err := postIdeaSummaries(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to post ideas summaries")
+			}
+			if err := archiveChannels(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to archive channels")
+			}
 		}
 	}
 }
 
-func announceBirthdays(db *DB, c *config, m *messages) error {
-	now := time.Now().In(c.Location)
+func findManagerDM(sc slack.Client, c *config) error {
+	mgrDM, err := sc.FindDMByUserID(c.ManagerID)
+	if err != nil {
+		return errors.Wrap(err, "unable to find manager's DM")
+	}
+	c.ManagerDM = mgrDM
+	return nil
+}
 
-	chMembers, err := slack.GetConversationMembers(c.LegacyToken, c.MainChannelID)
+// announceBirthdays sends the announcements of the provided kinds.
+// If since is not zero, the announcements missed after that time are sent as well.
+func announceBirthdays(sc slack.Client, clk clock, db *DB, c *config, m *messages, kinds announceKind, since time.Time) error {
+	plan, err := planBirthdays(sc, clk, db, c, since)
 	if err != nil {
-		return errors.Wrap(err, "unable to get channel")
+		return err
 	}
 
-	if len(chMembers) == 0 {
-		return errors.New("Slack channel is empty")
+	if kinds&announceManager == 0 {
+		plan.Manager, plan.AnnivManager, plan.EventManager = nil, nil, nil
+	}
+	if kinds&announceChannels == 0 {
+		plan.Channel, plan.AnnivChannel, plan.EventChannel = nil, nil, nil
 	}
 
-	logrus.Debugln("Members before blacklisting:", len(chMembers))
-	for i := 0; i < len(chMembers); i++ {
-		// remove blacklisted items
-		if stringInSlice(chMembers[i], c.Blacklist) {
-			logrus.Debugln("Blacklisting", chMembers[i])
-			chMembers = append(chMembers[:i], chMembers[i+1:]...)
-			i--
+	// birthdays can be sent to the manager as a digest
+	if mode := managerMode(db, c, c.ManagerID); mode != managerModeIndividual && kinds&announceManager != 0 {
+		if err := sendManagerDigest(sc, clk, db, c, m, mode, plan.Manager); err != nil {
+			logrus.WithError(err).Errorln("Unable to send manager digest")
 		}
+		plan.Manager = nil
 	}
-	logrus.Debugln("Members after blacklisting:", len(chMembers))
 
-	// create worker goroutine and gather results
-	var profiles []*slack.UserProfile
-	ch := make(chan *slack.UserProfile)
-	var wg sync.WaitGroup
-	wg.Add(len(chMembers))
-	for i := range chMembers {
-		go func(i int) {
-			defer wg.Done()
-			user, err := slack.GetUserProfile(c.LegacyToken, chMembers[i])
-			if err != nil {
-				logrus.WithError(err).Errorf("Unable to get user %s", chMembers[i])
-				return
-			}
-			logrus.Debugln("Adding", user.ID, user.RealName)
-			ch <- user
-		}(i)
+	for _, infoMap := range []map[string]bdInfo{plan.Manager, plan.AnnivManager, plan.EventManager} {
+		sendManagerAnnounces(sc, db, c, m, infoMap)
 	}
 
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
+	sendBelatedBDs(sc, db, c, m, plan.Belated)
 
-	for p := range ch {
-		profiles = append(profiles, p)
+	for _, infoMap := range []map[string]bdInfo{plan.Channel, plan.AnnivChannel, plan.EventChannel} {
+		if len(infoMap) == 0 {
+			continue
+		}
+		if err := sendBDsToNewChannels(sc, clk, db, c, m, infoMap); err != nil {
+			logrus.WithError(err).Errorf("Unable to send birthdays to channels")
+			return err
+		}
+	}
+
+	logrus.Infoln("Finished check, sleeping")
+	return nil
+}
+
+// planBirthdays decides which birthdays should be announced to the manager and which ones should get their channels.
+// If since is not zero, it also plans the announcements which were missed after that time.
+// It only reads from Slack and the cache, so it's safe to use for dry runs.
+func planBirthdays(sc slack.Client, clk clock, db *DB, c *config, since time.Time) (*announcePlan, error) {
+	now := clk.Now().In(c.Location)
+
+	profiles, err := mainChannelProfiles(sc, db, c)
+	if err != nil {
+		return nil, err
 	}
-	logrus.Infof("Main channel contains %d valid members", len(profiles))
 
-	managerAnnounceMap := make(map[string]bdInfo)
-	channelAnnounceMap := make(map[string]bdInfo)
+	plan := &announcePlan{
+		Manager:      make(map[string]bdInfo),
+		Channel:      make(map[string]bdInfo),
+		Belated:      make(map[string]bdInfo),
+		AnnivManager: make(map[string]bdInfo),
+		AnnivChannel: make(map[string]bdInfo),
+		EventManager: make(map[string]bdInfo),
+		EventChannel: make(map[string]bdInfo),
+	}
 	for _, p := range profiles {
-		logrus.Debugln(p.ID, p.RealName, p.Skype)
-		days, err := getUserBDInfo(now, p.Skype)
+		rawBD := userBirthday(p, c)
+		logrus.Debugln(p.ID, p.RealName, rawBD)
+		bd, err := parseBirthday(rawBD, c.DateOrder, now)
+		if err != nil {
+			// we can ignore this error, just log in debug mode
+			logrus.Debug(err)
+			continue
+		}
+
+		userBD := bd.DDMM()
+		days, bdDate, err := getUserBDInfo(now, userBD, c.LeapDay)
 		if err != nil {
 			// we can ignore this error, just log in debug mode
 			logrus.Debug(err)
 			continue
 		}
 
-		year, _, _ := now.Date()
-		currentBD := p.Skype + strconv.Itoa(year)
+		// cache records are bound to the year of the celebration, which can be the next one
+		currentBD := userBD + strconv.Itoa(bdDate.Year())
+
+		// previous celebration could have passed while the bot was down
+		if !since.IsZero() && days > 0 {
+			prevDate := celebrationDate(bdDate.Year()-1, bd.Month, bd.Day, c.LeapDay, c.Location)
+			if daysBetween(since, prevDate) > 0 && !isBDPrivate(db, p.ID) {
+				prevBD := userBD + strconv.Itoa(prevDate.Year())
+				ok, err := db.CheckUserBDInCache(db.ChannelBucketName, p.ID, prevBD)
+				if err != nil {
+					logrus.WithError(err).Errorf("Unable to check user %s in cache", p.ID)
+				} else if !ok {
+					logrus.Infof("User %s had birthday on %s, planning belated notice", p.ID, prevDate.Format("02.01.2006"))
+					plan.Belated[p.ID] = bdInfo{p.RealName, strings.ToLower(p.LastName), prevBD, daysBetween(now, prevDate), prevDate, eventBirthday, 0, nil}
+				}
+			}
+		}
+
+		// tresholds are applied to the date by which the announcements are due
+		window := announceWindow(now, bdDate, c)
+
+		// skip the people who chose to keep their birthday private
+		if window <= c.BDHighTreshold && isBDPrivate(db, p.ID) {
+			logrus.Infof("User %s has a private birthday, skipping", p.ID)
+			continue
+		}
 
 		// adding only the people who have BD in less than bdTreshold days
-		if days <= c.BDHighTreshold && days > c.BDLowTreshold {
+		if window <= c.BDHighTreshold && window > c.BDLowTreshold {
 			logrus.Infof("Checking manager cache for user %s", p.ID)
 			ok, err := db.CheckUserBDInCache(db.ManagerBucketName, p.ID, currentBD)
 			if err != nil {
@@ -234,9 +359,10 @@ func announceBirthdays(db *DB, c *config, m *messages) error {
 				continue
 			}
 
+			days = userDaysLeft(sc, c, p.ID, now, bdDate)
 			logrus.Infof("Informing manager about user %s (%d day(s) left)", p.ID, days)
-			managerAnnounceMap[p.ID] = bdInfo{p.RealName, strings.ToLower(p.LastName), currentBD, days}
-		} else if days <= c.BDLowTreshold {
+			plan.Manager[p.ID] = bdInfo{p.RealName, strings.ToLower(p.LastName), currentBD, days, bdDate, eventBirthday, 0, nil}
+		} else if window <= c.BDLowTreshold {
 			logrus.Infof("Checking channel cache for user %s", p.ID)
 			ok, err := db.CheckUserBDInCache(db.ChannelBucketName, p.ID, currentBD)
 			if err != nil {
@@ -246,54 +372,68 @@ func announceBirthdays(db *DB, c *config, m *messages) error {
 				continue
 			}
 
+			days = userDaysLeft(sc, c, p.ID, now, bdDate)
 			logrus.Infof("Creating channel about user %s (%d day(s) left)", p.ID, days)
-			channelAnnounceMap[p.ID] = bdInfo{p.RealName, strings.ToLower(p.LastName), currentBD, days}
+			plan.Channel[p.ID] = bdInfo{p.RealName, strings.ToLower(p.LastName), currentBD, days, bdDate, eventBirthday, 0, nil}
+
+			// manager should know about the birthday, if its window was missed while the bot was down
+			if !since.IsZero() && announceWindow(since, bdDate, c) > c.BDLowTreshold {
+				ok, err := db.CheckUserBDInCache(db.ManagerBucketName, p.ID, currentBD)
+				if err != nil {
+					logrus.WithError(err).Errorf("Unable to check user %s in cache", p.ID)
+				} else if !ok {
+					logrus.Infof("Informing manager about user %s late (%d day(s) left)", p.ID, days)
+					plan.Manager[p.ID] = plan.Channel[p.ID]
+				}
+			}
 		}
 	}
 
-	for id, info := range managerAnnounceMap {
-		if err := slack.SendAPIMessage(
-			c.LegacyToken, c.ManagerDM, fmt.Sprintf(m.ManagerAnnounce, id, info.DaysLeft),
-		); err != nil {
+	if c.Anniversaries {
+		for _, p := range profiles {
+			planAnniversary(now, p, db, c, plan)
+		}
+	}
+
+	if err = planEvents(now, db, c, plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// sendManagerAnnounces informs the manager about the upcoming events
+func sendManagerAnnounces(sc slack.Client, db *DB, c *config, m *messages, userInfoMap map[string]bdInfo) {
+	for id, info := range userInfoMap {
+		if err := sc.SendAPIMessage(c.ManagerDM, managerAnnounceText(m, id, info)); err != nil {
 			logrus.WithError(err).Errorf("Unable to send message to user %s", id)
 			continue
 		}
 
 		// add to cache
-		if err := db.SaveUserBDToCache(db.ManagerBucketName, id, info.Birthday); err != nil {
-			logrus.WithError(err).Errorf("Unable to save birthday in manager cache for user %s", id)
+		if err := db.SaveUserBDToCache(db.ManagerBucketName, info.cacheKey(id), info.Birthday); err != nil {
+			logrus.WithError(err).Errorf("Unable to save %s in manager cache for user %s", info.Kind, id)
 			continue
 		}
-		logrus.Infoln("Saved birthday in manager cache for user", id)
-	}
-
-	if len(channelAnnounceMap) > 0 {
-		if err := sendBDsToNewChannels(db, c, m.ChannelAnnounce, channelAnnounceMap); err != nil {
-			logrus.WithError(err).Errorf("Unable to send birthdays to channels")
-			return err
-		}
+		logrus.Infof("Saved %s in manager cache for user %s", info.Kind, id)
 	}
-
-	logrus.Infoln("Finished check, sleeping")
-	return nil
 }
 
-func sendBDsToNewChannels(db *DB, c *config, announce string, userInfoMap map[string]bdInfo) error {
+func sendBDsToNewChannels(sc slack.Client, clk clock, db *DB, c *config, m *messages, userInfoMap map[string]bdInfo) error {
 	for id, info := range userInfoMap {
 		// form the channel name
-		year := time.Now().In(c.Location).Year()
-		chanName := strings.Replace(fmt.Sprintf("%s-bd-%d", info.Surname, year), ".", "", -1)
+		chanName := bdChannelName(info)
 		logrus.Debugln("Creating new channel", chanName)
 
 		// create new private channel
-		chanID, err := slack.CreateNewConversation(c.LegacyToken, chanName, true)
+		chanID, err := sc.CreateNewConversation(chanName, true)
 		if err != nil {
 			if err.Error() != errorMsgNameTaken {
 				return errors.Wrapf(err, "unable to create channel with name %s", chanName)
 			}
 			logrus.Infof("Channel %s already exists, getting its ID...", chanName)
 
-			conversations, err := slack.GetConversations(c.LegacyToken, false)
+			conversations, err := sc.GetConversations(false)
 			if err != nil {
 				return errors.Wrap(err, "unable to get conversations")
 			}
@@ -311,74 +451,225 @@ func sendBDsToNewChannels(db *DB, c *config, announce string, userInfoMap map[st
 		}
 
 		// get main channel member list
-		members, err := slack.GetConversationMembers(c.LegacyToken, c.MainChannelID)
+		members, err := sc.GetConversationMembers(c.MainChannelID)
 		if err != nil {
 			return errors.Wrap(err, "unable to get main channel members")
 		}
 
-		// check blacklist
-		// skip manager, if it's not his/her birthday
-		logrus.Debugln("Members before blacklisting:", len(members))
-		for i := 0; i < len(members); i++ {
-			if stringInSlice(members[i], c.Blacklist) && members[i] != c.ManagerID || members[i] == id {
-				logrus.Debugln("Blacklisting", members[i])
-				members = append(members[:i], members[i+1:]...)
-				i--
-			}
-		}
-		logrus.Debugln("Members after blacklisting:", len(members))
-
 		// invite main channel members
-		err = slack.InviteMembersToConversation(c.LegacyToken, chanID, members)
+		invitees := bdChannelInvitees(members, c, info.honorees(id)...)
+		err = sc.InviteMembersToConversation(chanID, invitees)
 		if err != nil {
 			return errors.Wrapf(err, "unable to invite members to channel %s", chanName)
 		}
 
 		// send the greeting message
-		bdDate := info.Birthday[:2] + "." + info.Birthday[2:4] + "." + info.Birthday[4:]
-		if err := slack.SendAPIMessage(
-			c.LegacyToken, chanID, fmt.Sprintf(announce, id, info.RealName, bdDate, c.ManagerID),
-		); err != nil {
+		if err := sc.SendAPIMessage(chanID, channelAnnounceText(m, c, id, info)); err != nil {
 			return errors.Wrapf(err, "unable to send message to channel with ID %s", chanID)
 		}
 
+		// remember the channel, so that it's archived later
+		if err := recordChannel(db, chanID, chanName, id, info); err != nil {
+			logrus.WithError(err).Errorf("Unable to record channel %s", chanName)
+		}
+
+		// share the honoree's wishlist with the organisers
+		if info.Kind == eventBirthday {
+			if err := shareWishlist(sc, db, m, chanID, id, info); err != nil {
+				logrus.WithError(err).Errorf("Unable to share wishlist of user %s", id)
+			}
+		}
+
+		// start the money collection
+		if err := openCollection(db, c, chanID, id, info, invitees); err != nil {
+			logrus.WithError(err).Errorf("Unable to open collection in channel %s", chanName)
+		}
+
 		// add to cache
-		if err := db.SaveUserBDToCache(db.ChannelBucketName, id, info.Birthday); err != nil {
-			logrus.WithError(err).Errorf("Unable to save birthday in channel cache for user %s", id)
+		if err := db.SaveUserBDToCache(db.ChannelBucketName, info.cacheKey(id), info.Birthday); err != nil {
+			logrus.WithError(err).Errorf("Unable to save %s in channel cache for user %s", info.Kind, id)
 		} else {
-			logrus.Infoln("Saved birthday in channel cache for user", id)
+			logrus.Infof("Saved %s in channel cache for user %s", info.Kind, id)
 		}
 
-		logrus.Infof("Posted birthday message for the user %s in the channel %s", id, chanName)
+		logrus.Infof("Posted %s message for the user %s in the channel %s", info.Kind, id, chanName)
 	}
 
 	return nil
 }
 
-func getUserBDInfo(now time.Time, userBD string) (days int, err error) {
+// bdChannelName forms the name of the event channel
+func bdChannelName(info bdInfo) string {
+	var name string
+	switch info.Kind {
+	case eventAnniversary:
+		name = fmt.Sprintf("%s-anniversary-%d", info.Surname, info.Date.Year())
+	case eventCustom:
+		name = fmt.Sprintf("%s-%d", info.Surname, info.Date.Year())
+	default:
+		name = fmt.Sprintf("%s-bd-%d", info.Surname, info.Date.Year())
+	}
+	return strings.Replace(name, ".", "", -1)
+}
+
+// bdChannelInvitees filters the main channel members who should be invited to the honorees' event channel
+func bdChannelInvitees(members []string, c *config, honorees ...string) []string {
+	// check blacklist
+	// skip manager, if it's not his/her event
+	logrus.Debugln("Members before blacklisting:", len(members))
+	invitees := make([]string, 0, len(members))
+	for _, member := range members {
+		if stringInSlice(member, c.Blacklist) && member != c.ManagerID || stringInSlice(member, honorees) {
+			logrus.Debugln("Blacklisting", member)
+			continue
+		}
+		invitees = append(invitees, member)
+	}
+	logrus.Debugln("Members after blacklisting:", len(invitees))
+	return invitees
+}
+
+// managerAnnounceText formats the manager's message about the event
+func managerAnnounceText(m *messages, userID string, info bdInfo) string {
+	switch info.Kind {
+	case eventAnniversary:
+		return fmt.Sprintf(m.AnnivManagerAnnounce, userID, info.Years, info.DaysLeft)
+	case eventCustom:
+		return fmt.Sprintf(m.EventManagerAnnounce, info.RealName, mentions(info.Honorees), info.DaysLeft)
+	default:
+		return fmt.Sprintf(m.ManagerAnnounce, userID, info.DaysLeft)
+	}
+}
+
+// channelAnnounceText formats the greeting message for the event channel
+func channelAnnounceText(m *messages, c *config, userID string, info bdInfo) string {
+	switch info.Kind {
+	case eventAnniversary:
+		return fmt.Sprintf(m.AnnivChannelAnnounce, userID, info.RealName, info.Years, info.Date.Format("02.01.2006"), c.ManagerID)
+	case eventCustom:
+		return fmt.Sprintf(m.EventChannelAnnounce, info.RealName, mentions(info.Honorees), info.Date.Format("02.01.2006"), c.ManagerID)
+	default:
+		return fmt.Sprintf(m.ChannelAnnounce, userID, info.RealName, info.Date.Format("02.01.2006"), c.ManagerID)
+	}
+}
+
+// mainChannelProfiles returns the profiles of the main channel members, except the blacklisted and the opted out ones
+func mainChannelProfiles(sc slack.Client, db *DB, c *config) ([]*slack.UserProfile, error) {
+	chMembers, err := sc.GetConversationMembers(c.MainChannelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get channel")
+	}
+
+	if len(chMembers) == 0 {
+		return nil, errors.New("Slack channel is empty")
+	}
+
+	logrus.Debugln("Members before blacklisting:", len(chMembers))
+	for i := 0; i < len(chMembers); i++ {
+		// remove blacklisted items and the people who asked to be forgotten
+		if stringInSlice(chMembers[i], c.Blacklist) || isOptedOut(db, chMembers[i]) {
+			logrus.Debugln("Blacklisting", chMembers[i])
+			chMembers = append(chMembers[:i], chMembers[i+1:]...)
+			i--
+		}
+	}
+	logrus.Debugln("Members after blacklisting:", len(chMembers))
+
+	// create worker goroutine and gather results
+	var profiles []*slack.UserProfile
+	ch := make(chan *slack.UserProfile)
+	var wg sync.WaitGroup
+	wg.Add(len(chMembers))
+	for i := range chMembers {
+		go func(i int) {
+			defer wg.Done()
+			user, err := sc.GetUserProfile(chMembers[i])
+			if err != nil {
+				logrus.WithError(err).Errorf("Unable to get user %s", chMembers[i])
+				return
+			}
+			logrus.Debugln("Adding", user.ID, user.RealName)
+			ch <- user
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	for p := range ch {
+		profiles = append(profiles, p)
+	}
+	logrus.Infof("Main channel contains %d valid members", len(profiles))
+	return profiles, nil
+}
+
+// getUserBDInfo returns the amount of calendar days left until the next celebration of the DDMM birthday
+// and the date of that celebration. Days are counted in the location of now, zero means that birthday is today.
+func getUserBDInfo(now time.Time, userBD string, policy leapDayPolicy) (days int, date time.Time, err error) {
 	// we assume that people fill their BD date in the DDMM format
 	if len(userBD) != 4 {
-		return -1, errors.New("Skype field has wrong amount of symbols")
+		return -1, date, errors.New("birthday has wrong amount of symbols")
 	}
 
-	bd, err := time.Parse("02012006", userBD+"1900")
+	// use leap year to allow 29th of February
+	bd, err := time.Parse("02012006", userBD+"2000")
 	if err != nil {
-		return -1, errors.Wrap(err, "unable to parse birthday")
+		return -1, date, errors.Wrap(err, "unable to parse birthday")
 	}
-	// use the last ms of the day
-	bd = bd.Add(23*time.Hour + 59*time.Minute + 59*time.Second + 999*time.Millisecond)
-	logrus.Debugf("Got user's birthday: %s", bd)
+	logrus.Debugf("Got user's birthday: %s", bd.Format("02.01"))
 
-	ageBD := bd.AddDate(goage.Age(bd), 0, 0)
-	if now.After(ageBD) {
-		ageBD = ageBD.AddDate(1, 0, 0)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	date = celebrationDate(today.Year(), bd.Month(), bd.Day(), policy, now.Location())
+	if date.Before(today) {
+		date = celebrationDate(today.Year()+1, bd.Month(), bd.Day(), policy, now.Location())
 	}
 
-	days = int(ageBD.Sub(now).Hours()) / 24
+	days = daysBetween(today, date)
 	logrus.Debugf("Days left: %d", days)
 	return
 }
 
+// announceWindow returns the amount of days left until the announcements about the birthday are due.
+// If the birthday is a day off and shifting is enabled, they are due by the last working day before it.
+func announceWindow(now, bdDate time.Time, c *config) int {
+	due := bdDate
+	if c.ShiftAnnouncements {
+		due = c.Calendar.LastWorkday(bdDate)
+	}
+	if c.WorkdayTresholds {
+		return c.Calendar.WorkdaysBetween(now, due)
+	}
+	return daysBetween(now, due)
+}
+
+// userDaysLeft returns the amount of days left until the date in the user's own time zone
+func userDaysLeft(sc slack.Client, c *config, userID string, now, date time.Time) int {
+	days := daysBetween(now.In(userLocation(sc, c, userID)), date)
+	if days < 0 {
+		// the date has already passed for the user
+		return 0
+	}
+	return days
+}
+
+// daysBetween returns the amount of calendar days between the dates, ignoring the time of day and DST changes
+func daysBetween(from, to time.Time) int {
+	// UTC has no DST, so every day there is exactly 24 hours long
+	fromUTC := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toUTC := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toUTC.Sub(fromUTC).Hours() / 24)
+}
+
+// mentions formats the user IDs as Slack mentions
+func mentions(userIDs []string) string {
+	if len(userIDs) == 0 {
+		return ""
+	}
+	return "<@" + strings.Join(userIDs, ">, <@") + ">"
+}
+
 func stringInSlice(s string, ss []string) bool {
 	for i := range ss {
 		if ss[i] == s {
@@ -387,3 +678,12 @@ func stringInSlice(s string, ss []string) bool {
 	}
 	return false
 }
+
+func intInSlice(i int, is []int) bool {
+	for j := range is {
+		if is[j] == i {
+			return true
+		}
+	}
+	return false
+}
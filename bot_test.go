@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+// newTestBot creates the bot environment with the fake Slack workspace and a throwaway DB
+func newTestBot(t *testing.T) (*slack.FakeClient, *DB, *config, *messages) {
+	dir, err := ioutil.TempDir("", "bd-reminder-bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bolt.db")
+	db, err := openDB(&path, "manager", "channel", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	sc := slack.NewFakeClient("UBOT")
+	sc.AddUser(slack.UserProfile{ID: "UMGR", RealName: "Manager", LastName: "Manager"})
+	sc.AddUser(slack.UserProfile{ID: "U1", RealName: "Ann Lee", LastName: "Lee", Skype: "29.02"})
+	sc.AddUser(slack.UserProfile{ID: "U2", RealName: "Bob Ray", LastName: "Ray", Skype: "03.03"})
+	sc.AddChannel(slack.Conversation{ID: "CMAIN", Name: "general"}, "UBOT", "UMGR", "U1", "U2")
+
+	c := &config{
+		WorkdayStart: 10, WorkdayEnd: 19, Location: time.UTC,
+		BotUID: "UBOT", MainChannelID: "CMAIN", ManagerID: "UMGR",
+		BDHighTreshold: 7, BDLowTreshold: 3, Blacklist: []string{"UBOT"}, ManagerMode: managerModeIndividual,
+	}
+	if c.Calendar, err = newWorkCalendar(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = initBirthdaySources(c, db, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &messages{
+		ManagerAnnounce: "<@%s> has birthday in %d days",
+		ChannelAnnounce: "<@%s> (%s) has birthday at %s, <@%s> collects",
+	}
+	return sc, db, c, m
+}
+
+func TestPrintPlanHasNoSideEffects(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+
+	// 29 Feb birthday in a non-leap year is within the channel window, 3 Mar one is within the manager's
+	now := time.Date(2019, 2, 26, 10, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	if err := printPlan(&out, sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"<@U2> has birthday in 5 days", "lee-bd-2019"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the plan:\n%s", want, out.String())
+		}
+	}
+
+	for _, method := range []string{"SendAPIMessage", "CreateNewConversation", "InviteMembersToConversation"} {
+		if calls := sc.CallsTo(method); len(calls) != 0 {
+			t.Errorf("expected no %s calls, got %d", method, len(calls))
+		}
+	}
+	for _, bucket := range [][]byte{db.ManagerBucketName, db.ChannelBucketName} {
+		for _, id := range []string{"U1", "U2"} {
+			if value, _ := db.get(bucket, []byte(id)); value != nil {
+				t.Errorf("expected no %s cache record for %s, got %q", bucket, id, value)
+			}
+		}
+	}
+}
+
+func TestOptedOutUsersAreNotPlanned(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	if err := db.SaveUserOptOut("U2", true); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2019, 2, 26, 10, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	if err := printPlan(&out, sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "<@U2>") {
+		t.Errorf("expected no announcements of the opted out user:\n%s", out.String())
+	}
+
+	if err := optIn(db, "U2"); err != nil {
+		t.Fatal(err)
+	}
+	if isOptedOut(db, "U2") {
+		t.Error("expected the opt-out to be removed")
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestDaysBetween(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	la := mustLoadLocation(t, "America/Los_Angeles")
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		{"same day", time.Date(2019, 3, 24, 0, 5, 0, 0, la), time.Date(2019, 3, 24, 23, 55, 0, 0, la), 0},
+		{"spring forward in LA", time.Date(2019, 3, 10, 0, 0, 0, 0, la), time.Date(2019, 3, 11, 0, 0, 0, 0, la), 1},
+		{"across spring forward in LA", time.Date(2019, 3, 9, 23, 0, 0, 0, la), time.Date(2019, 3, 11, 0, 30, 0, 0, la), 2},
+		{"fall back in LA", time.Date(2019, 11, 3, 0, 0, 0, 0, la), time.Date(2019, 11, 4, 0, 0, 0, 0, la), 1},
+		{"spring forward in Moscow", time.Date(2010, 3, 27, 23, 30, 0, 0, moscow), time.Date(2010, 3, 28, 23, 30, 0, 0, moscow), 1},
+		{"fall back in Moscow", time.Date(2010, 10, 30, 0, 0, 0, 0, moscow), time.Date(2010, 11, 1, 0, 0, 0, 0, moscow), 2},
+		{"year wrap", time.Date(2019, 12, 31, 23, 59, 0, 0, la), time.Date(2020, 1, 1, 0, 0, 0, 0, la), 1},
+		{"backwards", time.Date(2020, 1, 1, 0, 0, 0, 0, moscow), time.Date(2019, 12, 30, 0, 0, 0, 0, moscow), -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daysBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetUserBDInfo(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	la := mustLoadLocation(t, "America/Los_Angeles")
+
+	// the same moment is still the birthday in LA, but is already the next day in Moscow
+	moment := time.Date(2019, 3, 25, 5, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		bd       string
+		wantDays int
+		wantDate string
+	}{
+		{"today", time.Date(2019, 3, 24, 23, 30, 0, 0, la), "2403", 0, "2019-03-24"},
+		{"today in LA", moment.In(la), "2403", 0, "2019-03-24"},
+		{"passed in Moscow", moment.In(moscow), "2403", 365, "2020-03-24"},
+		{"tomorrow", time.Date(2019, 3, 23, 0, 0, 0, 0, moscow), "2403", 1, "2019-03-24"},
+		{"year wrap", time.Date(2019, 12, 30, 12, 0, 0, 0, moscow), "0201", 3, "2020-01-02"},
+		{"year wrap at midnight", time.Date(2019, 12, 31, 23, 59, 0, 0, la), "0101", 1, "2020-01-01"},
+		{"across spring forward in LA", time.Date(2019, 3, 9, 12, 0, 0, 0, la), "1203", 3, "2019-03-12"},
+		{"across fall back in LA", time.Date(2019, 11, 2, 20, 0, 0, 0, la), "0411", 2, "2019-11-04"},
+		{"across fall back in Moscow", time.Date(2010, 10, 30, 10, 0, 0, 0, moscow), "0111", 2, "2010-11-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, date, err := getUserBDInfo(tt.now, tt.bd, leapDayFeb28)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if days != tt.wantDays || date.Format("2006-01-02") != tt.wantDate {
+				t.Errorf("expected %d day(s) until %s, got %d until %s", tt.wantDays, tt.wantDate, days, date.Format("2006-01-02"))
+			}
+			if date.Location() != tt.now.Location() {
+				t.Errorf("expected the date in %s, got %s", tt.now.Location(), date.Location())
+			}
+		})
+	}
+}
+
+func TestGetUserBDInfoInvalid(t *testing.T) {
+	now := time.Date(2019, 3, 24, 10, 0, 0, 0, time.UTC)
+	for _, bd := range []string{"", "243", "24031990", "3103x", "3202"} {
+		if _, _, err := getUserBDInfo(now, bd, leapDayFeb28); err == nil {
+			t.Errorf("expected an error for %q", bd)
+		}
+	}
+}
+
+func TestPlanBirthdaysDaysLeftInUserTimeZone(t *testing.T) {
+	sc, db, c, _ := newTestBot(t)
+	mustLoadLocation(t, "America/Los_Angeles")
+	sc.SetUserTZ("U2", "America/Los_Angeles")
+
+	// it's still 25 Feb in LA
+	now := time.Date(2019, 2, 26, 3, 0, 0, 0, time.UTC)
+	plan, err := planBirthdays(sc, fixedClock{now}, db, c, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, ok := plan.Manager["U2"]
+	if !ok {
+		t.Fatalf("expected U2 to be announced to the manager, got %+v", plan.Manager)
+	}
+	if info.DaysLeft != 6 {
+		t.Errorf("expected 6 days left in LA, got %d", info.DaysLeft)
+	}
+	if info.Date.Format("2006-01-02") != "2019-03-03" {
+		t.Errorf("expected the birthday on 2019-03-03, got %s", info.Date.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxNonWorkdays limits the search of the working days, in case of the misconfigured calendar
+const maxNonWorkdays = 366
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// workCalendar knows which days are working ones
+type workCalendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]bool // YYYY-MM-DD
+	yearly   map[string]bool // MM-DD, recurring holidays
+}
+
+// holidayRecord describes the holiday in YAML file
+type holidayRecord struct {
+	Date   string `yaml:"date"`
+	Name   string `yaml:"name"`
+	Yearly bool   `yaml:"yearly"`
+}
+
+// newWorkCalendar creates the calendar with the weekend days and the holidays loaded from the file, if it's set
+func newWorkCalendar(weekend []string, holidaysFile string) (*workCalendar, error) {
+	wc := &workCalendar{
+		weekend:  make(map[time.Weekday]bool, len(weekend)),
+		holidays: make(map[string]bool),
+		yearly:   make(map[string]bool),
+	}
+
+	for _, name := range weekend {
+		day, ok := weekdayNames[strings.ToLower(name)]
+		if !ok {
+			return nil, errors.Errorf("unknown weekday %q", name)
+		}
+		wc.weekend[day] = true
+	}
+	if len(wc.weekend) == len(weekdayNames) {
+		return nil, errors.New("there are no working days in the week")
+	}
+
+	if holidaysFile == "" {
+		return wc, nil
+	}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(holidaysFile)) {
+	case ".ics", ".ical":
+		err = wc.loadICal(holidaysFile)
+	case ".yaml", ".yml":
+		err = wc.loadYAML(holidaysFile)
+	default:
+		err = errors.Errorf("unsupported holidays file format %q", filepath.Ext(holidaysFile))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Loaded %d holidays and %d yearly holidays from %s", len(wc.holidays), len(wc.yearly), holidaysFile)
+	return wc, nil
+}
+
+// IsWorkday checks if the day is neither a weekend nor a holiday
+func (wc *workCalendar) IsWorkday(t time.Time) bool {
+	return !wc.weekend[t.Weekday()] && !wc.holidays[t.Format("2006-01-02")] && !wc.yearly[t.Format("01-02")]
+}
+
+// LastWorkday returns the start of the last working day on or before the provided day
+func (wc *workCalendar) LastWorkday(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i < maxNonWorkdays && !wc.IsWorkday(day); i++ {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// FirstWorkday returns the start of the first working day of the month of t
+func (wc *workCalendar) FirstWorkday(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	for i := 0; i < maxNonWorkdays && !wc.IsWorkday(day); i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// WorkdaysBetween returns the amount of working days after from and up to (including) to
+func (wc *workCalendar) WorkdaysBetween(from, to time.Time) int {
+	days := 0
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for n := daysBetween(from, to); n > 0; n-- {
+		day = day.AddDate(0, 0, 1)
+		if wc.IsWorkday(day) {
+			days++
+		}
+	}
+	return days
+}
+
+// loadYAML loads either the list of YYYY-MM-DD dates or the list of holiday records
+func (wc *workCalendar) loadYAML(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to read holidays file")
+	}
+
+	var records []holidayRecord
+	var dates []string
+	if err = yaml.Unmarshal(data, &dates); err == nil {
+		for _, d := range dates {
+			records = append(records, holidayRecord{Date: d})
+		}
+	} else if err = yaml.Unmarshal(data, &records); err != nil {
+		return errors.Wrap(err, "unable to parse holidays file")
+	}
+
+	for _, r := range records {
+		d, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			return errors.Wrapf(err, "wrong holiday date %q", r.Date)
+		}
+		wc.addHoliday(d, r.Yearly)
+	}
+	return nil
+}
+
+// loadICal loads all-day events from iCalendar file.
+// Events with yearly recurrence rule are treated as recurring holidays.
+func (wc *workCalendar) loadICal(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to read holidays file")
+	}
+
+	// unfold the lines according to RFC 5545
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	data = bytes.Replace(data, []byte("\n "), nil, -1)
+	data = bytes.Replace(data, []byte("\n\t"), nil, -1)
+
+	var (
+		inEvent    bool
+		start, end time.Time
+		yearly     bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		if i := strings.Index(name, ";"); i >= 0 {
+			name = name[:i] // skip the parameters
+		}
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, yearly = true, time.Time{}, time.Time{}, false
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			if len(value) < 8 {
+				return errors.Errorf("wrong %s value %q", name, value)
+			}
+			d, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return errors.Wrapf(err, "wrong %s value %q", name, value)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				start = d
+			} else {
+				end = d
+			}
+		case "RRULE":
+			yearly = inEvent && strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// DTEND is exclusive, single day event may have none
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				wc.addHoliday(d, yearly)
+			}
+		}
+	}
+	return errors.Wrap(scanner.Err(), "unable to read holidays file")
+}
+
+func (wc *workCalendar) addHoliday(d time.Time, yearly bool) {
+	if yearly {
+		wc.yearly[d.Format("01-02")] = true
+		return
+	}
+	wc.holidays[d.Format("2006-01-02")] = true
+}
+
+// NextWorkingTime returns the first schedule time strictly after t which falls on a working day.
+// Zero time is returned if there is none.
+func (wc *workCalendar) NextWorkingTime(s *schedule, t time.Time) time.Time {
+	next := s.Next(t)
+	for i := 0; i < maxNonWorkdays && !next.IsZero() && !wc.IsWorkday(next); i++ {
+		// skip the rest of the day off
+		next = s.Next(time.Date(next.Year(), next.Month(), next.Day(), 23, 59, 0, 0, next.Location()))
+	}
+	return next
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+const testICal = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20190101\r\n" +
+	"DTEND;VALUE=DATE:20190104\r\n" +
+	"SUMMARY:New Year\r\n" +
+	" holidays\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20190308\r\n" +
+	"SUMMARY:Women's Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20150509\r\n" +
+	"DTEND;VALUE=DATE:20150510\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"SUMMARY:Victory Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestNewWorkCalendar(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		weekend  []string
+		holidays []time.Time
+		workdays []time.Time
+	}{
+		{
+			"iCal", "holidays.ics", testICal, []string{"Saturday", "sunday"},
+			[]time.Time{testDate(2019, 1, 1), testDate(2019, 1, 3), testDate(2019, 3, 8), testDate(2019, 5, 9), testDate(2021, 5, 9),
+				testDate(2019, 3, 9), testDate(2019, 3, 10)},
+			[]time.Time{testDate(2019, 1, 4), testDate(2019, 3, 7), testDate(2019, 3, 11), testDate(2015, 5, 8)},
+		},
+		{
+			"YAML dates", "holidays.yaml", "- 2019-03-08\n- 2019-05-09\n", []string{"friday", "saturday"},
+			[]time.Time{testDate(2019, 3, 8), testDate(2019, 5, 9), testDate(2019, 3, 15), testDate(2019, 3, 16)},
+			[]time.Time{testDate(2020, 3, 8), testDate(2019, 5, 12), testDate(2019, 3, 17)},
+		},
+		{
+			"YAML records", "holidays.yml", "- date: 2019-03-08\n  name: Women's Day\n  yearly: true\n- date: 2019-05-10\n", nil,
+			[]time.Time{testDate(2019, 3, 8), testDate(2025, 3, 8), testDate(2019, 5, 10)},
+			[]time.Time{testDate(2020, 5, 10), testDate(2019, 3, 9), testDate(2019, 3, 10)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, err := newWorkCalendar(tt.weekend, writeTestFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range tt.holidays {
+				if wc.IsWorkday(d) {
+					t.Errorf("expected %s to be a day off", d.Format("2006-01-02"))
+				}
+			}
+			for _, d := range tt.workdays {
+				if !wc.IsWorkday(d) {
+					t.Errorf("expected %s to be a working day", d.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestNewWorkCalendarErrors(t *testing.T) {
+	allWeek := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	tests := []struct {
+		name    string
+		weekend []string
+		file    string
+		content string
+	}{
+		{"unknown weekday", []string{"caturday"}, "", ""},
+		{"no working days", allWeek, "", ""},
+		{"unknown format", nil, "holidays.txt", "2019-03-08\n"},
+		{"wrong YAML date", nil, "holidays.yaml", "- 08.03.2019\n"},
+		{"wrong iCal date", nil, "holidays.ics", "BEGIN:VEVENT\nDTSTART:2019\nEND:VEVENT\n"},
+	}
+	for _, tt := range tests {
+		path := ""
+		if tt.file != "" {
+			path = writeTestFile(t, tt.file, tt.content)
+		}
+		if _, err := newWorkCalendar(tt.weekend, path); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestAnnounceWindow(t *testing.T) {
+	wc, err := newWorkCalendar([]string{"saturday", "sunday"}, writeTestFile(t, "holidays.yaml", "- 2019-03-08\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := testDate(2019, 3, 1) // Friday
+	tests := []struct {
+		bd                 time.Time
+		shift, workdayDays bool
+		want               int
+	}{
+		// birthday on Monday
+		{testDate(2019, 3, 11), false, false, 10},
+		{testDate(2019, 3, 11), true, false, 10},
+		{testDate(2019, 3, 11), false, true, 5},
+		{testDate(2019, 3, 11), true, true, 5},
+		// birthday on Sunday after the holiday on Friday, so it's announced on Thursday
+		{testDate(2019, 3, 10), false, false, 9},
+		{testDate(2019, 3, 10), true, false, 6},
+		{testDate(2019, 3, 10), false, true, 4},
+		{testDate(2019, 3, 10), true, true, 4},
+		// birthday today
+		{now, true, true, 0},
+	}
+	for _, tt := range tests {
+		c := &config{Calendar: wc, ShiftAnnouncements: tt.shift, WorkdayTresholds: tt.workdayDays}
+		if got := announceWindow(now, tt.bd, c); got != tt.want {
+			t.Errorf("birthday %s, shift %t, workdays %t: expected %d, got %d",
+				tt.bd.Format("2006-01-02"), tt.shift, tt.workdayDays, tt.want, got)
+		}
+	}
+}
+
+func TestWorkdays(t *testing.T) {
+	wc, err := newWorkCalendar([]string{"saturday", "sunday"}, writeTestFile(t, "holidays.yaml", "- 2019-04-01\n- 2019-03-08\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := wc.LastWorkday(testDate(2019, 3, 10)), testDate(2019, 3, 7); !sameDay(got, want) {
+		t.Errorf("last workday: expected %s, got %s", want, got)
+	}
+	if got, want := wc.LastWorkday(testDate(2019, 3, 7)), testDate(2019, 3, 7); !sameDay(got, want) {
+		t.Errorf("last workday: expected %s, got %s", want, got)
+	}
+	if got, want := wc.FirstWorkday(testDate(2019, 6, 20)), testDate(2019, 6, 3); !sameDay(got, want) {
+		t.Errorf("first workday: expected %s, got %s", want, got)
+	}
+	if got, want := wc.FirstWorkday(testDate(2019, 4, 20)), testDate(2019, 4, 2); !sameDay(got, want) {
+		t.Errorf("first workday: expected %s, got %s", want, got)
+	}
+	if got := wc.WorkdaysBetween(testDate(2019, 3, 29), testDate(2019, 4, 3)); got != 2 {
+		t.Errorf("workdays between: expected 2, got %d", got)
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
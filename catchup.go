@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/sirupsen/logrus"
+)
+
+// catchUp replays the birthday checks which were missed since the last successful one
+func catchUp(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	last, err := db.GetLastCheck()
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now().In(c.Location)
+	if last.IsZero() || !checksMissed(c, last.In(c.Location), now) {
+		return nil
+	}
+
+	last = last.In(c.Location)
+	logrus.Infoln("Catching up the checks missed since", last.Format(time.RFC1123))
+	if err = announceBirthdays(sc, clk, db, c, m, announceManager|announceChannels, last); err != nil {
+		return err
+	}
+
+	if err = db.SaveLastCheck(now); err != nil {
+		logrus.WithError(err).Errorln("Unable to save last check time")
+	}
+	return nil
+}
+
+// checksMissed checks if any of the scheduled checks should have been run between the last check and now
+func checksMissed(c *config, last, now time.Time) bool {
+	for _, s := range []*schedule{c.ManagerSchedule, c.ChannelSchedule} {
+		if next := c.Calendar.NextWorkingTime(s, last); !next.IsZero() && !next.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendBelatedBDs informs the manager about the birthdays which passed while the bot was down
+func sendBelatedBDs(sc slack.Client, db *DB, c *config, m *messages, userInfoMap map[string]bdInfo) {
+	if m.BelatedAnnounce == "" {
+		return
+	}
+
+	for id, info := range userInfoMap {
+		if err := sc.SendAPIMessage(
+			c.ManagerDM, fmt.Sprintf(m.BelatedAnnounce, id, info.Date.Format("02.01.2006")),
+		); err != nil {
+			logrus.WithError(err).Errorf("Unable to send belated message about user %s", id)
+			continue
+		}
+
+		// mark the celebration as handled
+		if err := db.SaveUserBDToCache(db.ChannelBucketName, id, info.Birthday); err != nil {
+			logrus.WithError(err).Errorf("Unable to save birthday in channel cache for user %s", id)
+			continue
+		}
+		logrus.Infoln("Sent belated notice about user", id)
+	}
+}
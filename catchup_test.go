@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestCatchUpMissedChecks(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	sc.AddUser(slack.UserProfile{ID: "U3", RealName: "Cid Doe", LastName: "Doe", Skype: "24.02"})
+	sc.AddChannel(slack.Conversation{ID: "CMAIN", Name: "general"}, "UBOT", "UMGR", "U1", "U2", "U3")
+	c.ManagerDM = "DUMGR"
+	c.LeapDay = leapDayMar1
+	m.BelatedAnnounce = "<@%s> had birthday at %s"
+	var err error
+	if c.ManagerSchedule, err = parseSchedule("0 10 * * *"); err != nil {
+		t.Fatal(err)
+	}
+	if c.ChannelSchedule, err = parseSchedule("0 10 * * *"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bot was down since 20 Feb: U3 birthday has passed,
+	// U1 (1 Mar in non-leap year) and U2 (3 Mar) are within the channel window already
+	last := time.Date(2019, 2, 20, 10, 0, 0, 0, time.UTC)
+	if err = db.SaveLastCheck(last); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 2, 28, 12, 0, 0, 0, time.UTC)
+	if err = catchUp(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+
+	var dms []string
+	for _, msg := range sc.SentMessages(c.ManagerDM) {
+		dms = append(dms, msg.Text)
+	}
+	for _, want := range []string{
+		"<@U3> had birthday at 24.02.2019",
+		"<@U1> has birthday in 1 days",
+		"<@U2> has birthday in 3 days",
+	} {
+		if !stringInSlice(want, dms) {
+			t.Errorf("expected %q in manager DMs, got %q", want, dms)
+		}
+	}
+	if len(dms) != 3 {
+		t.Errorf("expected 3 manager DMs, got %q", dms)
+	}
+
+	var channels []string
+	for _, call := range sc.CallsTo("CreateNewConversation") {
+		channels = append(channels, call.Args[0].(string))
+	}
+	sort.Strings(channels)
+	if strings.Join(channels, ",") != "lee-bd-2019,ray-bd-2019" {
+		t.Errorf("expected channels for U1 and U2, got %q", channels)
+	}
+
+	for _, tt := range []struct {
+		bucket []byte
+		id, bd string
+	}{
+		{db.ChannelBucketName, "U3", "24022019"},
+		{db.ManagerBucketName, "U1", "29022019"},
+		{db.ManagerBucketName, "U2", "03032019"},
+		{db.ChannelBucketName, "U2", "03032019"},
+	} {
+		if ok, err := db.CheckUserBDInCache(tt.bucket, tt.id, tt.bd); err != nil || !ok {
+			t.Errorf("expected %s of %s in %s cache (error %v)", tt.bd, tt.id, tt.bucket, err)
+		}
+	}
+
+	saved, err := db.GetLastCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Equal(now) {
+		t.Errorf("expected last check at %s, got %s", now, saved)
+	}
+
+	// nothing is missed anymore
+	calls := len(sc.Calls)
+	if err = catchUp(sc, fixedClock{now.Add(time.Hour)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Calls) != calls {
+		t.Errorf("expected no calls on the second catch up, got %+v", sc.Calls[calls:])
+	}
+}
+
+func TestCatchUpWithoutLastCheck(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	now := time.Date(2019, 2, 28, 12, 0, 0, 0, time.UTC)
+	if err := catchUp(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Calls) != 0 {
+		t.Errorf("expected no calls without the last check, got %+v", sc.Calls)
+	}
+}
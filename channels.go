@@ -0,0 +1,183 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const commandCleanup = "cleanup"
+
+// errors returned for the channels which can't be archived by the bot anymore
+var goneChannelErrors = []string{
+	"API error: already_archived",
+	"API error: is_archived",
+	"API error: channel_not_found",
+	"API error: not_in_channel",
+}
+
+// leftoverChannelRe matches the names of the birthday and anniversary channels created before they were recorded
+var leftoverChannelRe = regexp.MustCompile(`-(?:bd|anniversary)-(\d{4})$`)
+
+// createdChannel describes the event channel created by the bot
+type createdChannel struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Honoree  string    `json:"honoree"` // user or event ID
+	Kind     eventKind `json:"kind"`
+	Date     string    `json:"date"` // YYYY-MM-DD, date of the celebration
+	Archived bool      `json:"archived,omitempty"`
+}
+
+// recordChannel saves the event channel created by the bot
+func recordChannel(db *DB, chanID, chanName, id string, info bdInfo) error {
+	return db.SaveCreatedChannel(createdChannel{chanID, chanName, id, info.Kind, info.Date.Format("2006-01-02"), false})
+}
+
+// archiveChannels archives the recorded channels after the grace period, if it's enabled
+func archiveChannels(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	if !c.ArchiveChannels {
+		return nil
+	}
+
+	_, err := archiveRecordedChannels(sc, clk, db, c, m, false)
+	return err
+}
+
+// archiveRecordedChannels archives the recorded channels whose grace period has passed and returns their amount.
+// If dryRun is set, the channels are only listed in the log.
+func archiveRecordedChannels(sc slack.Client, clk clock, db *DB, c *config, m *messages, dryRun bool) (int, error) {
+	channels, err := db.GetCreatedChannels()
+	if err != nil {
+		return 0, err
+	}
+
+	now := clk.Now().In(c.Location)
+	archived := 0
+	for _, ch := range channels {
+		if ch.Archived {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", ch.Date, c.Location)
+		if err != nil {
+			logrus.WithError(err).Errorf("Wrong date of channel %s", ch.Name)
+			continue
+		}
+		if daysBetween(date, now) <= c.ArchiveGraceDays {
+			continue
+		}
+
+		if dryRun {
+			logrus.Infoln("Would archive channel", ch.Name)
+			archived++
+			continue
+		}
+		if err = archiveChannel(sc, m, ch.ID); err != nil {
+			logrus.WithError(err).Errorf("Unable to archive channel %s", ch.Name)
+			continue
+		}
+
+		ch.Archived = true
+		if err = db.SaveCreatedChannel(ch); err != nil {
+			logrus.WithError(err).Errorf("Unable to save channel %s", ch.Name)
+			continue
+		}
+		logrus.Infoln("Archived channel", ch.Name)
+		archived++
+	}
+	return archived, nil
+}
+
+// archiveChannel posts the closing message and archives the channel.
+// Channels which were archived or removed by someone else, or which the bot was removed from, are treated as archived.
+func archiveChannel(sc slack.Client, m *messages, chanID string) error {
+	if m.ChannelClosing != "" {
+		if err := sc.SendAPIMessage(chanID, m.ChannelClosing); err != nil {
+			if isChannelGone(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "unable to send message to channel with ID %s", chanID)
+		}
+	}
+
+	if err := sc.ArchiveConversation(chanID); err != nil && !isChannelGone(err) {
+		return errors.Wrapf(err, "unable to archive channel with ID %s", chanID)
+	}
+	return nil
+}
+
+// isChannelGone checks if the error means that the channel can't be archived by the bot anymore
+func isChannelGone(err error) bool {
+	return stringInSlice(err.Error(), goneChannelErrors)
+}
+
+// cleanupChannels archives the recorded channels whose grace period has passed,
+// as well as the birthday and anniversary channels of the previous years which were never recorded.
+// Private '*-bd-YYYY' or '*-anniversary-YYYY' channel is considered a leftover only if it was created with the bot's token
+// and the bot is still its member. The celebration date of the leftover is unknown, so the grace period starts at its creation.
+// Custom event channels can't be told apart from the other ones by their names, so they're never touched.
+// If dryRun is set, the channels are only listed in the log.
+func cleanupChannels(sc slack.Client, clk clock, db *DB, c *config, m *messages, dryRun bool) error {
+	archived, err := archiveRecordedChannels(sc, clk, db, c, m, dryRun)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := db.GetCreatedChannels()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(recorded))
+	for _, ch := range recorded {
+		known[ch.ID] = true
+	}
+
+	conversations, err := sc.GetConversations(false)
+	if err != nil {
+		return errors.Wrap(err, "unable to get conversations")
+	}
+
+	// channels are created with the legacy token, so its owner is their creator
+	creator, err := sc.AuthTest()
+	if err != nil {
+		return errors.Wrap(err, "unable to get the token owner")
+	}
+
+	now := clk.Now().In(c.Location)
+	for _, conv := range conversations {
+		parts := leftoverChannelRe.FindStringSubmatch(conv.Name)
+		if known[conv.ID] || parts == nil || !conv.IsPrivate || !conv.IsMember || conv.Creator != creator {
+			continue
+		}
+		if chanYear, _ := strconv.Atoi(parts[1]); chanYear >= now.Year() {
+			continue
+		}
+		if daysBetween(time.Unix(conv.Created, 0).In(c.Location), now) <= c.ArchiveGraceDays {
+			continue
+		}
+
+		if dryRun {
+			logrus.Infoln("Would archive leftover channel", conv.Name)
+			archived++
+			continue
+		}
+		if err = archiveChannel(sc, m, conv.ID); err != nil {
+			logrus.WithError(err).Errorf("Unable to archive channel %s", conv.Name)
+			continue
+		}
+		logrus.Infoln("Archived leftover channel", conv.Name)
+		archived++
+	}
+
+	if dryRun {
+		logrus.Infof("Cleanup dry run is finished, %d channel(s) would be archived", archived)
+		return nil
+	}
+	logrus.Infof("Cleanup is finished, archived %d channel(s)", archived)
+	return nil
+}
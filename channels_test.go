@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestArchiveChannelsArchivedBySomeoneElse(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	c.ArchiveChannels, c.ArchiveGraceDays = true, 7
+	m.ChannelClosing = "Bye"
+
+	sc.AddChannel(slack.Conversation{ID: "COPEN", Name: "lee-bd-2019", IsPrivate: true}, "UBOT")
+	sc.AddChannel(slack.Conversation{ID: "CDONE", Name: "ray-bd-2019", IsPrivate: true, IsArchived: true}, "UBOT")
+	date := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for id, name := range map[string]string{"COPEN": "lee-bd-2019", "CDONE": "ray-bd-2019", "CGONE": "doe-bd-2019"} {
+		if err := recordChannel(db, id, name, "U1", bdInfo{Date: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2019, 3, 20, 10, 0, 0, 0, time.UTC)
+	if err := archiveChannels(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+
+	channels, err := db.GetCreatedChannels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range channels {
+		if !ch.Archived {
+			t.Errorf("expected channel %s to be marked as archived", ch.Name)
+		}
+	}
+	if !sc.Channels["COPEN"].IsArchived {
+		t.Error("expected the open channel to be archived")
+	}
+	if sent := sc.SentMessages("COPEN"); len(sent) != 1 {
+		t.Errorf("expected the closing message in the open channel, got %+v", sent)
+	}
+}
+
+func TestCleanupChannelsDryRun(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	created := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	sc.AddChannel(slack.Conversation{ID: "COLD", Name: "lee-bd-2018", IsPrivate: true, IsMember: true, Creator: "UBOT", Created: created}, "UBOT")
+	sc.AddChannel(slack.Conversation{ID: "CEVENT", Name: "team-day-2018", IsPrivate: true, IsMember: true, Creator: "UBOT", Created: created}, "UBOT")
+
+	now := time.Date(2019, 3, 20, 10, 0, 0, 0, time.UTC)
+	if err := cleanupChannels(sc, fixedClock{now}, db, c, m, true); err != nil {
+		t.Fatal(err)
+	}
+	if calls := sc.CallsTo("ArchiveConversation"); len(calls) != 0 {
+		t.Errorf("expected no channels to be archived in dry run, got %+v", calls)
+	}
+
+	if err := cleanupChannels(sc, fixedClock{now}, db, c, m, false); err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Channels["COLD"].IsArchived {
+		t.Error("expected the leftover channel to be archived")
+	}
+	if sc.Channels["CEVENT"].IsArchived {
+		t.Error("expected the event channel to be kept")
+	}
+}
+
+func TestCleanupChannelsLeavesOthersAlone(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	c.ArchiveGraceDays = 7
+	m.ChannelClosing = "Bye"
+	old := time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC).Unix()
+	recent := time.Date(2018, 12, 28, 0, 0, 0, 0, time.UTC).Unix()
+	for _, conv := range []slack.Conversation{
+		{ID: "COLD", Name: "lee-bd-2018", IsPrivate: true, IsMember: true, Creator: "UBOT", Created: old},
+		{ID: "CSMITH", Name: "smith-bd-2018", IsPrivate: true, IsMember: true, Creator: "U1", Created: old},
+		{ID: "CLEFT", Name: "ray-bd-2018", IsPrivate: true, IsMember: false, Creator: "UBOT", Created: old},
+		{ID: "CDEC", Name: "doe-bd-2018", IsPrivate: true, IsMember: true, Creator: "UBOT", Created: recent},
+	} {
+		sc.AddChannel(conv, "UBOT")
+	}
+
+	// Dec 31 birthday channel is still within its grace period on Jan 1
+	now := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	if err := cleanupChannels(sc, fixedClock{now}, db, c, m, false); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{"COLD": true, "CSMITH": false, "CLEFT": false, "CDEC": false} {
+		if got := sc.Channels[id].IsArchived; got != want {
+			t.Errorf("channel %s: expected archived %t, got %t", sc.Channels[id].Name, want, got)
+		}
+	}
+	if sent := sc.SentMessages("CSMITH"); len(sent) != 0 {
+		t.Errorf("expected no closing message in the channel of someone else, got %+v", sent)
+	}
+
+	// grace period has passed
+	if err := cleanupChannels(sc, fixedClock{now.AddDate(0, 0, 7)}, db, c, m, false); err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Channels["CDEC"].IsArchived {
+		t.Error("expected the channel to be archived after the grace period")
+	}
+	if sc.Channels["CSMITH"].IsArchived {
+		t.Error("expected the channel of someone else to be kept")
+	}
+}
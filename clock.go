@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// simulated date formats, from the most to the least precise
+var simulatedDateFormats = []string{
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// clock is the source of the current time
+type clock interface {
+	Now() time.Time
+}
+
+// realClock returns the actual current time
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// fixedClock always returns the same time, used for date simulation
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+// parseSimulatedDate parses the date in the provided location.
+// If the time of day is omitted, start of the workday is used.
+func parseSimulatedDate(s string, loc *time.Location, workdayStart int) (time.Time, error) {
+	for i, f := range simulatedDateFormats {
+		t, err := time.ParseInLocation(f, s, loc)
+		if err != nil {
+			continue
+		}
+		if i == len(simulatedDateFormats)-1 {
+			t = t.Add(time.Duration(workdayStart) * time.Hour)
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.Errorf("date %q doesn't match any of the formats %v", s, simulatedDateFormats)
+}
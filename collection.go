@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandPaid   = "paid"
+	commandSkip   = "skip"
+	commandStatus = "status"
+)
+
+var (
+	// errCollectionNotFound is returned when the channel has no open collection
+	errCollectionNotFound = errors.New("there's no open collection in the channel")
+	// errCollectionUnchanged is returned by the collection changes which have nothing to do, so nothing is saved
+	errCollectionUnchanged = errors.New("collection is unchanged")
+)
+
+// collection describes the money collection in the event channel
+type collection struct {
+	ChannelID     string             `json:"channel_id"`
+	Title         string             `json:"title"`     // name of the honoree or the event title
+	Date          string             `json:"date"`      // YYYY-MM-DD, date of the celebration
+	Collector     string             `json:"collector"` // user ID
+	Honorees      []string           `json:"honorees,omitempty"`
+	Contributions map[string]float64 `json:"contributions,omitempty"`
+	Invitees      []string           `json:"invitees,omitempty"`
+	Skipped       []string           `json:"skipped,omitempty"`  // members who won't contribute
+	Reminded      []int              `json:"reminded,omitempty"` // days before the celebration when the reminders were sent
+	Closed        bool               `json:"closed,omitempty"`
+
+	Ideas           []giftIdea `json:"ideas,omitempty"`
+	IdeasSummarized bool       `json:"ideas_summarized,omitempty"`
+}
+
+// newCollection creates the collection for the event channel
+func newCollection(chanID string, c *config, id string, info bdInfo, invitees []string) collection {
+	return collection{
+		ChannelID:     chanID,
+		Title:         info.RealName,
+		Date:          info.Date.Format("2006-01-02"),
+		Collector:     c.ManagerID,
+		Honorees:      info.honorees(id),
+		Contributions: make(map[string]float64),
+		Invitees:      invitees,
+	}
+}
+
+// collectorIsHonoree checks if the collection is for the collector's own celebration,
+// so that the gift stays a surprise for them
+func (col *collection) collectorIsHonoree() bool {
+	return stringInSlice(col.Collector, col.Honorees)
+}
+
+// total returns the sum of the contributions
+func (col *collection) total() float64 {
+	var sum float64
+	for _, amount := range col.Contributions {
+		sum += amount
+	}
+	return sum
+}
+
+// pending returns the invited members who have neither contributed nor skipped the collection
+func (col *collection) pending() []string {
+	var ids []string
+	for _, id := range col.Invitees {
+		if _, ok := col.Contributions[id]; ok || id == col.Collector || stringInSlice(id, col.Skipped) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// dueReminder checks if any of the reminder days has come and marks them as reminded
+func (col *collection) dueReminder(reminderDays []int, days int) bool {
+	due := false
+	for _, d := range reminderDays {
+		if days <= d && !intInSlice(d, col.Reminded) {
+			col.Reminded = append(col.Reminded, d)
+			due = true
+		}
+	}
+	return due
+}
+
+// openCollection creates the collection for the new event channel, existing one is kept
+func openCollection(db *DB, c *config, chanID, id string, info bdInfo, invitees []string) error {
+	existing, err := db.GetCollection(chanID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	if err = db.SaveCollection(newCollection(chanID, c, id, info, invitees)); err != nil {
+		return err
+	}
+	logrus.Infof("Opened collection in channel %s", chanID)
+	return nil
+}
+
+// closeCollections closes the collections after their celebrations and sends the results to the collectors
+func closeCollections(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	collections, err := db.GetCollections()
+	if err != nil {
+		return err
+	}
+
+	today := clk.Now().In(c.Location).Format("2006-01-02")
+	for _, col := range collections {
+		// dates are in the same format, so they can be compared as strings
+		if col.Closed || col.Date >= today {
+			continue
+		}
+
+		// collection is changed in a single transaction, so that the contributions made meanwhile are not lost
+		err = db.UpdateCollection(col.ChannelID, func(current *collection) error {
+			if current.Closed {
+				return errCollectionUnchanged
+			}
+			current.Closed = true
+			col = *current
+			return nil
+		})
+		if err == errCollectionUnchanged {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to close collection in channel %s", col.ChannelID)
+			continue
+		}
+		logrus.Infof("Closed collection in channel %s", col.ChannelID)
+
+		if col.collectorIsHonoree() {
+			logrus.Infof("Collector %s is the honoree, not sending the results of collection in channel %s", col.Collector, col.ChannelID)
+			continue
+		}
+		dm, err := sc.FindDMByUserID(col.Collector)
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to find DM of collector %s", col.Collector)
+			continue
+		}
+		if err = sc.SendAPIMessage(dm, collectionText(m.CollectionClosed, col)); err != nil {
+			logrus.WithError(err).Errorf("Unable to send collection results to %s", col.Collector)
+		}
+	}
+	return nil
+}
+
+// remindContributors sends the reminders to the members who haven't contributed yet,
+// on the configured days before the celebration
+func remindContributors(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	if len(c.ReminderDays) == 0 {
+		return nil
+	}
+
+	collections, err := db.GetCollections()
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now().In(c.Location)
+	for _, col := range collections {
+		date, err := time.ParseInLocation("2006-01-02", col.Date, c.Location)
+		if err != nil {
+			logrus.WithError(err).Errorf("Wrong date of collection in channel %s", col.ChannelID)
+			continue
+		}
+
+		// reminders stop at the celebration date
+		days := daysBetween(now, date)
+		if col.Closed || days <= 0 {
+			continue
+		}
+
+		// save first, so that nobody is reminded twice
+		var pending []string
+		err = db.UpdateCollection(col.ChannelID, func(current *collection) error {
+			if current.Closed || !current.dueReminder(c.ReminderDays, days) {
+				return errCollectionUnchanged
+			}
+			pending = current.pending()
+			return nil
+		})
+		if err == errCollectionUnchanged {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to save reminders of collection in channel %s", col.ChannelID)
+			continue
+		}
+
+		for _, id := range pending {
+			dm, err := sc.FindDMByUserID(id)
+			if err != nil {
+				logrus.WithError(err).Errorf("Unable to find DM of user %s", id)
+				continue
+			}
+			if err = sc.SendAPIMessage(dm, fmt.Sprintf(m.CollectionReminder, id, col.Title, days, col.ChannelID)); err != nil {
+				logrus.WithError(err).Errorf("Unable to send reminder to user %s", id)
+				continue
+			}
+			logrus.Infof("Reminded user %s about collection in channel %s", id, col.ChannelID)
+		}
+	}
+	return nil
+}
+
+// parseAmount parses the contributed amount, zero means it wasn't specified
+func parseAmount(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	amount, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, errors.Errorf("%q is not an amount", s)
+	}
+	return amount, nil
+}
+
+// formatAmount formats the amount without the trailing zeroes
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// collectionText formats the collection summary: title, total, amount of contributors and their list
+func collectionText(format string, col collection) string {
+	ids := make([]string, 0, len(col.Contributions))
+	for id := range col.Contributions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	lines := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		line := "• <@" + id + ">"
+		if amount := col.Contributions[id]; amount > 0 {
+			line += " " + formatAmount(amount)
+		}
+		lines = append(lines, line)
+	}
+	if pending := col.pending(); len(pending) > 0 {
+		lines = append(lines, "Pending: "+mentions(pending))
+	}
+	return fmt.Sprintf(format, col.Title, formatAmount(col.total()), len(ids), strings.Join(lines, "\n"))
+}
+
+// channelCollection returns the open collection of the channel or replies to the user, if there is none
+func channelCollection(rtm slack.RTM, db *DB, msgs *messages, m slack.Message) *collection {
+	col, err := db.GetCollection(m.Conversation)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get collection of channel %s", m.Conversation)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return nil
+	}
+	if col == nil || col.Closed {
+		sendReply(rtm, m, fmt.Sprintf(msgs.CollectionNotFound, m.User))
+		return nil
+	}
+	return col
+}
+
+// updateChannelCollection changes the open collection of the channel in a single transaction.
+// If it's not possible, the user gets the reply and false is returned.
+// Errors of fn are shown to the user with the usage message, which gets the user ID and the error.
+func updateChannelCollection(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, usage string, fn func(col *collection) error) bool {
+	var fnErr error
+	err := db.UpdateCollection(m.Conversation, func(col *collection) error {
+		if col.Closed {
+			return errCollectionNotFound
+		}
+		fnErr = fn(col)
+		return fnErr
+	})
+
+	switch {
+	case err == nil:
+		return true
+	case err == errCollectionNotFound:
+		sendReply(rtm, m, fmt.Sprintf(msgs.CollectionNotFound, m.User))
+	case fnErr != nil:
+		sendReply(rtm, m, fmt.Sprintf(usage, m.User, fnErr))
+	default:
+		logrus.WithError(err).Errorf("Unable to update collection of channel %s", m.Conversation)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+	}
+	return false
+}
+
+// handlePaid records the user's contribution, repeated command replaces the amount
+func handlePaid(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, args string) {
+	amount, err := parseAmount(args)
+	if err != nil {
+		sendReply(rtm, m, fmt.Sprintf(msgs.AmountError, m.User, err))
+		return
+	}
+
+	ok := updateChannelCollection(rtm, db, msgs, m, msgs.CollectionUsage, func(col *collection) error {
+		if col.Contributions == nil {
+			col.Contributions = make(map[string]float64)
+		}
+		col.Contributions[m.User] = amount
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	logrus.Infof("User %s paid %s in channel %s", m.User, formatAmount(amount), m.Conversation)
+	sendReply(rtm, m, fmt.Sprintf(msgs.CollectionPaid, m.User))
+}
+
+// handleSkip marks that the user won't contribute, so no reminders are sent to them
+func handleSkip(rtm slack.RTM, db *DB, msgs *messages, m slack.Message) {
+	ok := updateChannelCollection(rtm, db, msgs, m, msgs.CollectionUsage, func(col *collection) error {
+		if !stringInSlice(m.User, col.Skipped) {
+			col.Skipped = append(col.Skipped, m.User)
+		}
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	logrus.Infof("User %s skipped collection in channel %s", m.User, m.Conversation)
+	sendReply(rtm, m, fmt.Sprintf(msgs.CollectionSkipped, m.User))
+}
+
+// handleStatus shows the running total and the contributors to the collector
+func handleStatus(rtm slack.RTM, db *DB, msgs *messages, m slack.Message) {
+	col := channelCollection(rtm, db, msgs, m)
+	if col == nil {
+		return
+	}
+
+	if m.User != col.Collector || col.collectorIsHonoree() {
+		sendReply(rtm, m, fmt.Sprintf(msgs.NotAllowed, m.User))
+		return
+	}
+	sendReply(rtm, m, collectionText(msgs.CollectionStatus, *col))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"500", 500, false},
+		{"12,5", 12.5, false},
+		{"-1", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"-Inf", 0, true},
+		{"1e400", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAmount(%q): expected %v (error %t), got %v (%v)", tt.in, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestConcurrentContributions(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	m.CollectionPaid, m.CollectionNotFound = "<@%s>, saved", "<@%s>, no collection"
+	m.CollectionReminder, m.CollectionClosed = "<@%s> %s %d <#%s>", "%s: %s from %d\n%s"
+	c.ReminderDays = []int{3}
+
+	var invitees []string
+	for i := 0; i < 20; i++ {
+		invitees = append(invitees, fmt.Sprintf("U%02d", i))
+	}
+	info := bdInfo{RealName: "Ann Lee", Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := openCollection(db, c, "CBD", "UHERO", info, invitees); err != nil {
+		t.Fatal(err)
+	}
+
+	// contributions are made while the watcher sends the reminders
+	now := time.Date(2019, 2, 26, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for _, id := range invitees {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			handlePaid(slack.NewFakeRTM(), db, m, slack.Message{User: id, Conversation: "CBD"}, "100")
+		}(id)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := remindContributors(sc, fixedClock{now}, db, c, m); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	col, err := db.GetCollection("CBD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(col.Contributions) != len(invitees) {
+		t.Errorf("expected %d contributions, got %d", len(invitees), len(col.Contributions))
+	}
+	if !intInSlice(3, col.Reminded) {
+		t.Errorf("expected the reminder to be marked, got %v", col.Reminded)
+	}
+
+	// closed collection takes no more contributions
+	if err = closeCollections(sc, fixedClock{now.AddDate(0, 0, 4)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	rtm := slack.NewFakeRTM()
+	handlePaid(rtm, db, m, slack.Message{User: "U99", Conversation: "CBD"}, "100")
+	if sent := rtm.SentMessages(); len(sent) != 1 || sent[0].Text != "<@U99>, no collection" {
+		t.Errorf("expected the collection to be closed, got %+v", sent)
+	}
+}
+
+func TestHandlePaidWrongAmount(t *testing.T) {
+	_, db, c, m := newTestBot(t)
+	m.AmountError = "<@%s>, %s. Try `paid 500`"
+	m.CollectionPaid = "<@%s>, thanks"
+	info := bdInfo{RealName: "Ann Lee", Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := openCollection(db, c, "CBD", "UHERO", info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rtm := slack.NewFakeRTM()
+	handlePaid(rtm, db, m, slack.Message{User: "U1", Conversation: "CBD"}, "abc")
+	handlePaid(rtm, db, m, slack.Message{User: "U1", Conversation: "CBD"}, "500")
+
+	sent := rtm.SentMessages()
+	if len(sent) != 2 || sent[0].Text != `<@U1>, "abc" is not an amount. Try `+"`paid 500`" || sent[1].Text != "<@U1>, thanks" {
+		t.Errorf("unexpected replies %+v", sent)
+	}
+}
+
+func TestCollectionForCollectorsOwnCelebration(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	m.NotAllowed = "<@%s>, not allowed"
+	m.CollectionStatus = "Collection for %s: %s from %d people\n%s"
+	m.CollectionClosed = "Closed %s: %s from %d people\n%s"
+	info := bdInfo{RealName: "Manager", Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := openCollection(db, c, "CMGR", "UMGR", info, []string{"U1", "U2"}); err != nil {
+		t.Fatal(err)
+	}
+	info.RealName = "Ann Lee"
+	if err := openCollection(db, c, "CBD", "U1", info, []string{"UMGR", "U2"}); err != nil {
+		t.Fatal(err)
+	}
+	handlePaid(slack.NewFakeRTM(), db, m, slack.Message{User: "U2", Conversation: "CMGR"}, "100")
+
+	rtm := slack.NewFakeRTM()
+	handleStatus(rtm, db, m, slack.Message{User: "UMGR", Conversation: "CMGR"})
+	handleStatus(rtm, db, m, slack.Message{User: "UMGR", Conversation: "CBD"})
+	sent := rtm.SentMessages()
+	if len(sent) != 2 || sent[0].Text != "<@UMGR>, not allowed" || sent[1].Text != "Collection for Ann Lee: 0 from 0 people\nPending: <@U2>" {
+		t.Errorf("unexpected replies %+v", sent)
+	}
+
+	if err := closeCollections(sc, fixedClock{time.Date(2019, 3, 2, 10, 0, 0, 0, time.UTC)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	dms := sc.SentMessages("DUMGR")
+	if len(dms) != 1 || dms[0].Text != "Closed Ann Lee: 0 from 0 people\nPending: <@U2>" {
+		t.Errorf("expected the results of the other collection only, got %+v", dms)
+	}
+	for _, id := range []string{"CMGR", "CBD"} {
+		if col, err := db.GetCollection(id); err != nil || !col.Closed {
+			t.Errorf("expected collection %s to be closed (error %v)", id, err)
+		}
+	}
+}
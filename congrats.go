@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// hourlySchedule fires at the start of every hour, so that every time zone gets its local hour
+var hourlySchedule, _ = parseSchedule("0 * * * *")
+
+// congratsWatcher sends the birthday congratulations every hour to the people whose local hour has come.
+// Failed attempts are logged and retried on the next hour, the birthdays which were not congratulated stay out of the cache.
+func congratsWatcher(ctx context.Context, sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	// first start, in case the hour has passed while the bot was down
+	if err := congratulate(sc, clk, db, c, m); err != nil {
+		logrus.WithError(err).Errorln("Unable to congratulate")
+	}
+
+	for {
+		now := clk.Now()
+		next := hourlySchedule.Next(now)
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			logrus.Warn("Stopping congratulations watcher")
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			if err := congratulate(sc, clk, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Unable to congratulate")
+			}
+		}
+	}
+}
+
+// congratulate sends the birthday congratulations to the people
+// whose birthday is today and whose local time is past the configured hour.
+// Each birthday is congratulated once, congratulated ones are saved in the cache.
+func congratulate(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	profiles, err := mainChannelProfiles(sc, db, c)
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now()
+	for _, p := range profiles {
+		bd, err := parseBirthday(userBirthday(p, c), c.DateOrder, now)
+		if err != nil {
+			// we can ignore this error, just log in debug mode
+			logrus.Debug(err)
+			continue
+		}
+
+		// don't ask Slack for the time zone, if the birthday is not today anywhere
+		if !bdTodayAnywhere(now, bd, c.LeapDay) {
+			continue
+		}
+
+		local := now.In(userLocation(sc, c, p.ID))
+		if local.Hour() < c.CongratsHour {
+			continue
+		}
+		days, bdDate, err := getUserBDInfo(local, bd.DDMM(), c.LeapDay)
+		if err != nil || days != 0 {
+			continue
+		}
+
+		currentBD := bd.DDMM() + strconv.Itoa(bdDate.Year())
+		ok, err := db.CheckUserBDInCache(db.CongratsBucketName, p.ID, currentBD)
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to check user %s in cache", p.ID)
+			continue
+		} else if ok {
+			continue
+		}
+
+		if err := congratulateUser(sc, db, c, m, p.ID); err != nil {
+			logrus.WithError(err).Errorf("Unable to congratulate user %s, retrying next hour", p.ID)
+			continue
+		}
+
+		// add to cache
+		if err := db.SaveUserBDToCache(db.CongratsBucketName, p.ID, currentBD); err != nil {
+			logrus.WithError(err).Errorf("Unable to save birthday in congrats cache for user %s", p.ID)
+			continue
+		}
+		logrus.Infof("Congratulated user %s at %s", p.ID, local.Format(time.RFC1123))
+	}
+	return nil
+}
+
+// congratulateUser sends the congratulations to the user's DM and the main channel, if they're enabled.
+// The whole congratulation is retried on error, so the DM can be repeated.
+func congratulateUser(sc slack.Client, db *DB, c *config, m *messages, userID string) error {
+	if c.CongratsDM {
+		dm, err := sc.FindDMByUserID(userID)
+		if err != nil {
+			return errors.Wrap(err, "unable to find DM")
+		}
+		if err = sc.SendAPIMessage(dm, fmt.Sprintf(m.CongratsDM, userID)); err != nil {
+			return errors.Wrap(err, "unable to send DM")
+		}
+	}
+
+	// private birthdays are not announced to the team
+	if c.CongratsChannel && !isBDPrivate(db, userID) {
+		if err := sc.SendAPIMessage(c.MainChannelID, fmt.Sprintf(m.CongratsChannel, userID)); err != nil {
+			return errors.Wrap(err, "unable to send message to the main channel")
+		}
+	}
+	return nil
+}
+
+// bdTodayAnywhere checks if the birthday is today in any time zone.
+// Local date is always either the date at UTC-12 or the date at UTC+14.
+func bdTodayAnywhere(now time.Time, bd bdDate, policy leapDayPolicy) bool {
+	for _, offset := range []time.Duration{-12 * time.Hour, 14 * time.Hour} {
+		if days, _, err := getUserBDInfo(now.UTC().Add(offset), bd.DDMM(), policy); err == nil && days == 0 {
+			return true
+		}
+	}
+	return false
+}
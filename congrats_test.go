@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCongratulate(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	c.CongratsHour, c.CongratsDM, c.CongratsChannel = 10, true, true
+	m.CongratsDM = "Happy birthday, <@%s>!"
+	m.CongratsChannel = "Today is <@%s> birthday!"
+	sc.SetUserTZ("U2", "America/Los_Angeles")
+
+	// it's still early morning for U2
+	now := time.Date(2019, 3, 3, 12, 0, 0, 0, time.UTC)
+	if err := congratulate(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if calls := sc.CallsTo("SendAPIMessage"); len(calls) != 0 {
+		t.Fatalf("expected no congratulations before the local hour, got %+v", calls)
+	}
+
+	// main channel is not available, so the birthday is retried
+	sc.Channels["CMAIN"].IsArchived = true
+	now = time.Date(2019, 3, 3, 18, 0, 0, 0, time.UTC)
+	if err := congratulate(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.CheckUserBDInCache(db.CongratsBucketName, "U2", "03032019"); err != nil || ok {
+		t.Fatalf("expected failed congratulation to stay out of the cache (error %v)", err)
+	}
+
+	sc.Channels["CMAIN"].IsArchived = false
+	now = now.Add(time.Hour)
+	if err := congratulate(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.CheckUserBDInCache(db.CongratsBucketName, "U2", "03032019"); err != nil || !ok {
+		t.Fatalf("expected congratulation to be cached (error %v)", err)
+	}
+	if sent := sc.SentMessages("CMAIN"); len(sent) != 1 || sent[0].Text != "Today is <@U2> birthday!" {
+		t.Errorf("expected the congratulation in the main channel, got %+v", sent)
+	}
+	// DM was sent on both attempts
+	if sent := sc.SentMessages("DU2"); len(sent) != 2 || sent[1].Text != "Happy birthday, <@U2>!" {
+		t.Errorf("expected the congratulation in DM, got %+v", sent)
+	}
+
+	// congratulated only once
+	calls := len(sc.CallsTo("SendAPIMessage"))
+	if err := congratulate(sc, fixedClock{now.Add(time.Hour)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sc.CallsTo("SendAPIMessage")); got != calls {
+		t.Errorf("expected no more congratulations, got %d new messages", got-calls)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"time"
 
 	bolt "github.com/coreos/bbolt"
@@ -10,8 +11,14 @@ import (
 
 // DB is a cache, wraps bolt.DB
 type DB struct {
-	ManagerBucketName []byte
-	ChannelBucketName []byte
+	ManagerBucketName    []byte
+	ChannelBucketName    []byte
+	CongratsBucketName   []byte
+	UserBucketName       []byte
+	StateBucketName      []byte
+	EventBucketName      []byte
+	CollectionBucketName []byte
+	CreatedBucketName    []byte
 
 	*bolt.DB
 }
@@ -19,7 +26,44 @@ type DB struct {
 // DefaultDBTimeout for Bolt
 const DefaultDBTimeout = 1 * time.Second
 
-func openDB(path *string, mBucket, cBucket string, timeout time.Duration) (*DB, error) {
+// DefaultUserBucket stores the birthdays set by users themselves
+const DefaultUserBucket = "user"
+
+// DefaultCongratsBucket stores the birthdays which were congratulated
+const DefaultCongratsBucket = "congrats"
+
+// DefaultStateBucket stores the bot's own state
+const DefaultStateBucket = "state"
+
+// DefaultEventBucket stores the custom events
+const DefaultEventBucket = "event"
+
+// DefaultCollectionBucket stores the money collections, by channel ID
+const DefaultCollectionBucket = "collection"
+
+// DefaultCreatedBucket stores the event channels created by the bot, by channel ID
+const DefaultCreatedBucket = "created_channel"
+
+// state keys
+const (
+	lastCheckKey   = "last_check"
+	digestMonthKey = "digest_month"
+
+	managerModeKeyPrefix   = "manager_mode/"
+	managerDigestKeyPrefix = "manager_digest/"
+	removedEventKeyPrefix  = "removed_event/"
+)
+
+// startDateKeyPrefix separates the start dates from the birthdays in the user bucket
+const startDateKeyPrefix = "start_date/"
+
+// optOutKeyPrefix separates the opt-outs from the birthdays in the user bucket
+const optOutKeyPrefix = "opt_out/"
+
+// wishlistKeyPrefix separates the wishlists from the birthdays in the user bucket
+const wishlistKeyPrefix = "wishlist/"
+
+func openDB(path *string, mBucket, cBucket, gBucket, uBucket string, timeout time.Duration) (*DB, error) {
 	if timeout == 0 {
 		timeout = DefaultDBTimeout
 	}
@@ -29,7 +73,14 @@ func openDB(path *string, mBucket, cBucket string, timeout time.Duration) (*DB,
 	if err != nil {
 		return nil, err
 	}
-	db := &DB{[]byte(mBucket), []byte(cBucket), boltDB}
+	if gBucket == "" {
+		gBucket = DefaultCongratsBucket
+	}
+	if uBucket == "" {
+		uBucket = DefaultUserBucket
+	}
+	db := &DB{[]byte(mBucket), []byte(cBucket), []byte(gBucket), []byte(uBucket),
+		[]byte(DefaultStateBucket), []byte(DefaultEventBucket), []byte(DefaultCollectionBucket), []byte(DefaultCreatedBucket), boltDB}
 
 	// create buckets if needed
 	if err = db.newBucket(db.ManagerBucketName); err != nil {
@@ -38,13 +89,31 @@ func openDB(path *string, mBucket, cBucket string, timeout time.Duration) (*DB,
 	if err = db.newBucket(db.ChannelBucketName); err != nil {
 		return nil, err
 	}
+	if err = db.newBucket(db.CongratsBucketName); err != nil {
+		return nil, err
+	}
+	if err = db.newBucket(db.UserBucketName); err != nil {
+		return nil, err
+	}
+	if err = db.newBucket(db.StateBucketName); err != nil {
+		return nil, err
+	}
+	if err = db.newBucket(db.EventBucketName); err != nil {
+		return nil, err
+	}
+	if err = db.newBucket(db.CollectionBucketName); err != nil {
+		return nil, err
+	}
+	if err = db.newBucket(db.CreatedBucketName); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
 
 // SaveUserBDToCache saves the record about the user's birthday into the cache DB's bucket
 func (db *DB) SaveUserBDToCache(bucketName []byte, id, bd string) error {
-	if !bytes.Equal(bucketName, db.ManagerBucketName) && !bytes.Equal(bucketName, db.ChannelBucketName) {
+	if !db.isCacheBucket(bucketName) {
 		return errors.Errorf("bucket %q does not exist", bucketName)
 	}
 
@@ -57,7 +126,7 @@ func (db *DB) SaveUserBDToCache(bucketName []byte, id, bd string) error {
 
 // CheckUserBDInCache checks if the record about the user's birthday is present in the cache DB's bucket
 func (db *DB) CheckUserBDInCache(bucketName []byte, id, bd string) (bool, error) {
-	if !bytes.Equal(bucketName, db.ManagerBucketName) && !bytes.Equal(bucketName, db.ChannelBucketName) {
+	if !db.isCacheBucket(bucketName) {
 		return false, errors.Errorf("bucket %q does not exist", bucketName)
 	}
 
@@ -76,6 +145,440 @@ func (db *DB) CheckUserBDInCache(bucketName []byte, id, bd string) (bool, error)
 	return false, nil
 }
 
+// SaveUserBD saves the birthday set by the user
+func (db *DB) SaveUserBD(id string, bd userBD) error {
+	value, err := json.Marshal(bd)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal birthday")
+	}
+
+	if err := db.put(db.UserBucketName, []byte(id), value); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetUserBD returns the birthday set by the user or nil, if there is none
+func (db *DB) GetUserBD(id string) (*userBD, error) {
+	value, err := db.get(db.UserBucketName, []byte(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	bd := &userBD{}
+	if err = json.Unmarshal(value, bd); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal birthday")
+	}
+	return bd, nil
+}
+
+// DeleteUserBD removes the birthday set by the user
+func (db *DB) DeleteUserBD(id string) error {
+	if err := db.delete(db.UserBucketName, []byte(id)); err != nil {
+		return errors.Wrap(err, "unable to delete value from DB")
+	}
+
+	return nil
+}
+
+// SaveUserStartDate saves the work start date set by the user
+func (db *DB) SaveUserStartDate(id, date string) error {
+	if err := db.put(db.UserBucketName, []byte(startDateKeyPrefix+id), []byte(date)); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetUserStartDate returns the work start date set by the user or empty string, if there is none
+func (db *DB) GetUserStartDate(id string) (string, error) {
+	value, err := db.get(db.UserBucketName, []byte(startDateKeyPrefix+id))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return string(value), nil
+}
+
+// DeleteUserStartDate removes the work start date set by the user
+func (db *DB) DeleteUserStartDate(id string) error {
+	if err := db.delete(db.UserBucketName, []byte(startDateKeyPrefix+id)); err != nil {
+		return errors.Wrap(err, "unable to delete value from DB")
+	}
+
+	return nil
+}
+
+// SaveUserOptOut saves or removes the user's choice to be left out of all of the announcements
+func (db *DB) SaveUserOptOut(id string, optOut bool) error {
+	if !optOut {
+		if err := db.delete(db.UserBucketName, []byte(optOutKeyPrefix+id)); err != nil {
+			return errors.Wrap(err, "unable to delete value from DB")
+		}
+		return nil
+	}
+
+	if err := db.put(db.UserBucketName, []byte(optOutKeyPrefix+id), []byte("1")); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// IsUserOptedOut checks if the user has chosen to be left out of all of the announcements
+func (db *DB) IsUserOptedOut(id string) (bool, error) {
+	value, err := db.get(db.UserBucketName, []byte(optOutKeyPrefix+id))
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return value != nil, nil
+}
+
+// SaveWishlist saves the user's wishlist, empty one is removed
+func (db *DB) SaveWishlist(id string, wishes []string) error {
+	if len(wishes) == 0 {
+		return db.DeleteWishlist(id)
+	}
+
+	value, err := json.Marshal(wishes)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal wishlist")
+	}
+
+	if err := db.put(db.UserBucketName, []byte(wishlistKeyPrefix+id), value); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetWishlist returns the user's wishlist
+func (db *DB) GetWishlist(id string) ([]string, error) {
+	value, err := db.get(db.UserBucketName, []byte(wishlistKeyPrefix+id))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var wishes []string
+	if err = json.Unmarshal(value, &wishes); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal wishlist")
+	}
+	return wishes, nil
+}
+
+// DeleteWishlist removes the user's wishlist
+func (db *DB) DeleteWishlist(id string) error {
+	if err := db.delete(db.UserBucketName, []byte(wishlistKeyPrefix+id)); err != nil {
+		return errors.Wrap(err, "unable to delete value from DB")
+	}
+
+	return nil
+}
+
+// SaveEvent saves the custom event, replacing the one with the same ID.
+// Event saved again after the removal is no longer marked as removed.
+func (db *DB) SaveEvent(e customEvent) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal event")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		events, state := tx.Bucket(db.EventBucketName), tx.Bucket(db.StateBucketName)
+		if events == nil || state == nil {
+			return errors.New("event buckets not found")
+		}
+
+		if err := events.Put([]byte(e.ID), value); err != nil {
+			return err
+		}
+		return state.Delete([]byte(removedEventKeyPrefix + e.ID))
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetEvents returns all of the custom events
+func (db *DB) GetEvents() ([]customEvent, error) {
+	var events []customEvent
+	err := db.forEach(db.EventBucketName, func(k, v []byte) error {
+		var e customEvent
+		if err := json.Unmarshal(v, &e); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal event %s", k)
+		}
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get values from DB")
+	}
+
+	return events, nil
+}
+
+// GetEvent returns the custom event or nil, if there's none with this ID
+func (db *DB) GetEvent(id string) (*customEvent, error) {
+	value, err := db.get(db.EventBucketName, []byte(id))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	e := &customEvent{}
+	if err = json.Unmarshal(value, e); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal event")
+	}
+	return e, nil
+}
+
+// DeleteEvent removes the custom event and marks it as removed, so that it's not imported again
+func (db *DB) DeleteEvent(id string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		events, state := tx.Bucket(db.EventBucketName), tx.Bucket(db.StateBucketName)
+		if events == nil || state == nil {
+			return errors.New("event buckets not found")
+		}
+
+		if err := events.Delete([]byte(id)); err != nil {
+			return err
+		}
+		return state.Put([]byte(removedEventKeyPrefix+id), []byte("1"))
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to delete value from DB")
+	}
+
+	return nil
+}
+
+// IsEventRemoved checks if the custom event was removed by the manager
+func (db *DB) IsEventRemoved(id string) (bool, error) {
+	value, err := db.get(db.StateBucketName, []byte(removedEventKeyPrefix+id))
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return value != nil, nil
+}
+
+// SaveCollection saves the money collection, replacing the one of the same channel
+func (db *DB) SaveCollection(col collection) error {
+	value, err := json.Marshal(col)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal collection")
+	}
+
+	if err := db.put(db.CollectionBucketName, []byte(col.ChannelID), value); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetCollection returns the money collection of the channel or nil, if there is none
+func (db *DB) GetCollection(chanID string) (*collection, error) {
+	value, err := db.get(db.CollectionBucketName, []byte(chanID))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	col := &collection{}
+	if err = json.Unmarshal(value, col); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal collection")
+	}
+	return col, nil
+}
+
+// UpdateCollection changes the money collection of the channel in a single transaction, so that the concurrent changes are not lost.
+// Nothing is saved if fn returns an error, errCollectionNotFound is returned if the channel has no collection.
+func (db *DB) UpdateCollection(chanID string, fn func(col *collection) error) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(db.CollectionBucketName)
+		if bucket == nil {
+			return errors.Errorf("bucket %q not found", db.CollectionBucketName)
+		}
+
+		value := bucket.Get([]byte(chanID))
+		if value == nil {
+			return errCollectionNotFound
+		}
+
+		col := &collection{}
+		if err := json.Unmarshal(value, col); err != nil {
+			return errors.Wrap(err, "unable to unmarshal collection")
+		}
+		if err := fn(col); err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(col)
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal collection")
+		}
+		return bucket.Put([]byte(chanID), value)
+	})
+}
+
+// GetCollections returns all of the money collections
+func (db *DB) GetCollections() ([]collection, error) {
+	var collections []collection
+	err := db.forEach(db.CollectionBucketName, func(k, v []byte) error {
+		var col collection
+		if err := json.Unmarshal(v, &col); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal collection %s", k)
+		}
+		collections = append(collections, col)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get values from DB")
+	}
+
+	return collections, nil
+}
+
+// SaveCreatedChannel saves the record about the event channel created by the bot
+func (db *DB) SaveCreatedChannel(ch createdChannel) error {
+	value, err := json.Marshal(ch)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal channel")
+	}
+
+	if err := db.put(db.CreatedBucketName, []byte(ch.ID), value); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetCreatedChannels returns all of the event channels created by the bot
+func (db *DB) GetCreatedChannels() ([]createdChannel, error) {
+	var channels []createdChannel
+	err := db.forEach(db.CreatedBucketName, func(k, v []byte) error {
+		var ch createdChannel
+		if err := json.Unmarshal(v, &ch); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal channel %s", k)
+		}
+		channels = append(channels, ch)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get values from DB")
+	}
+
+	return channels, nil
+}
+
+// SaveLastCheck saves the time of the last successful birthday check
+func (db *DB) SaveLastCheck(t time.Time) error {
+	if err := db.put(db.StateBucketName, []byte(lastCheckKey), []byte(t.Format(time.RFC3339))); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetLastCheck returns the time of the last successful birthday check or zero time, if there was none
+func (db *DB) GetLastCheck() (time.Time, error) {
+	value, err := db.get(db.StateBucketName, []byte(lastCheckKey))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "unable to get value from DB")
+	}
+
+	if value == nil {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, string(value))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "unable to parse last check time")
+	}
+	return t, nil
+}
+
+// SaveDigestMonth saves the month of the last posted monthly digest, in the YYYY-MM format
+func (db *DB) SaveDigestMonth(month string) error {
+	if err := db.put(db.StateBucketName, []byte(digestMonthKey), []byte(month)); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetDigestMonth returns the month of the last posted monthly digest or empty string, if there was none
+func (db *DB) GetDigestMonth() (string, error) {
+	value, err := db.get(db.StateBucketName, []byte(digestMonthKey))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return string(value), nil
+}
+
+// SaveManagerMode saves the announcement mode chosen by the manager
+func (db *DB) SaveManagerMode(id, mode string) error {
+	if err := db.put(db.StateBucketName, []byte(managerModeKeyPrefix+id), []byte(mode)); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetManagerMode returns the announcement mode chosen by the manager or empty string, if there is none
+func (db *DB) GetManagerMode(id string) (string, error) {
+	value, err := db.get(db.StateBucketName, []byte(managerModeKeyPrefix+id))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return string(value), nil
+}
+
+// SaveManagerDigestPeriod saves the period of the last digest sent to the manager
+func (db *DB) SaveManagerDigestPeriod(id, period string) error {
+	if err := db.put(db.StateBucketName, []byte(managerDigestKeyPrefix+id), []byte(period)); err != nil {
+		return errors.Wrap(err, "unable to put value into DB")
+	}
+
+	return nil
+}
+
+// GetManagerDigestPeriod returns the period of the last digest sent to the manager or empty string, if there was none
+func (db *DB) GetManagerDigestPeriod(id string) (string, error) {
+	value, err := db.get(db.StateBucketName, []byte(managerDigestKeyPrefix+id))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get value from DB")
+	}
+
+	return string(value), nil
+}
+
+func (db *DB) isCacheBucket(bucketName []byte) bool {
+	return bytes.Equal(bucketName, db.ManagerBucketName) ||
+		bytes.Equal(bucketName, db.ChannelBucketName) ||
+		bytes.Equal(bucketName, db.CongratsBucketName)
+}
+
 func (db *DB) newBucket(bucketName []byte) error {
 	return db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists(bucketName)
@@ -111,3 +614,25 @@ func (db *DB) get(bucketName, key []byte) (value []byte, err error) {
 	})
 	return
 }
+
+func (db *DB) forEach(bucketName []byte, fn func(k, v []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return errors.Errorf("bucket %q not found", bucketName)
+		}
+
+		return bucket.ForEach(fn)
+	})
+}
+
+func (db *DB) delete(bucketName, key []byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return errors.Errorf("bucket %q not found", bucketName)
+		}
+
+		return bucket.Delete(key)
+	})
+}
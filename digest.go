@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// monthlyDigestBD describes the birthday in the monthly digest
+type monthlyDigestBD struct {
+	RealName string
+	Date     time.Time
+}
+
+// postMonthlyDigest posts the list of the month's birthdays, starting from the first working day of the month.
+// Digest is posted once per month, posted months are saved in the DB.
+func postMonthlyDigest(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	if !c.MonthlyDigest {
+		return nil
+	}
+
+	now := clk.Now().In(c.Location)
+	if now.Before(c.Calendar.FirstWorkday(now)) {
+		return nil
+	}
+
+	month := now.Format("2006-01")
+	posted, err := db.GetDigestMonth()
+	if err != nil {
+		return err
+	}
+	if posted == month {
+		return nil
+	}
+
+	profiles, err := mainChannelProfiles(sc, db, c)
+	if err != nil {
+		return err
+	}
+
+	text := monthlyDigestText(m, now, monthlyBirthdays(now, profiles, db, c))
+	if text != "" {
+		if err = sc.SendAPIMessage(c.DigestChannelID, text); err != nil {
+			return errors.Wrap(err, "unable to send monthly digest")
+		}
+		logrus.Infoln("Posted monthly digest for", month)
+	}
+
+	return db.SaveDigestMonth(month)
+}
+
+// monthlyBirthdays returns the public birthdays of the month of now, sorted by date
+func monthlyBirthdays(now time.Time, profiles []*slack.UserProfile, db *DB, c *config) []monthlyDigestBD {
+	var list []monthlyDigestBD
+	for _, p := range profiles {
+		bd, err := parseBirthday(userBirthday(p, c), c.DateOrder, now)
+		if err != nil {
+			continue
+		}
+
+		date := celebrationDate(now.Year(), bd.Month, bd.Day, c.LeapDay, now.Location())
+		if date.Month() != now.Month() || isBDPrivate(db, p.ID) {
+			continue
+		}
+		list = append(list, monthlyDigestBD{p.RealName, date})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Date.Equal(list[j].Date) {
+			return list[i].Date.Before(list[j].Date)
+		}
+		return list[i].RealName < list[j].RealName
+	})
+	return list
+}
+
+// monthlyDigestText formats the digest, empty string is returned if there are no birthdays
+func monthlyDigestText(m *messages, now time.Time, list []monthlyDigestBD) string {
+	if len(list) == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(list))
+	for _, bd := range list {
+		lines = append(lines, fmt.Sprintf("• %s %s", bd.Date.Format("02.01"), bd.RealName))
+	}
+	return fmt.Sprintf(m.MonthlyDigest, now.Format("January 2006"), strings.Join(lines, "\n"))
+}
+
+const commandDigest = "digest"
+
+// manager announcement modes
+const (
+	managerModeIndividual = "individual"
+	managerModeDaily      = "daily"
+	managerModeWeekly     = "weekly"
+)
+
+// parseManagerMode parses the manager announcement mode
+func parseManagerMode(s string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(s)); mode {
+	case "":
+		return managerModeIndividual, nil
+	case managerModeIndividual, managerModeDaily, managerModeWeekly:
+		return mode, nil
+	default:
+		return "", errors.Errorf("unknown mode %q, expected %q, %q or %q", s, managerModeIndividual, managerModeDaily, managerModeWeekly)
+	}
+}
+
+// managerMode returns the manager's own announcement mode or the configured one, if it's not set
+func managerMode(db *DB, c *config, userID string) string {
+	mode, err := db.GetManagerMode(userID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get announcement mode of user %s", userID)
+	}
+	if mode == "" {
+		return c.ManagerMode
+	}
+	return mode
+}
+
+// digestPeriod returns the key of the digest period containing now, or empty string for individual messages
+func digestPeriod(mode string, now time.Time) string {
+	switch mode {
+	case managerModeDaily:
+		return now.Format("2006-01-02")
+	case managerModeWeekly:
+		year, week := now.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return ""
+	}
+}
+
+// digestDays returns the amount of days covered by the digest,
+// so that every birthday gets into a digest before the channel is created
+func digestDays(mode string, c *config) int {
+	if mode == managerModeWeekly {
+		return c.BDHighTreshold + 6
+	}
+	return c.BDHighTreshold
+}
+
+// sendManagerDigest sends the manager a single message with the upcoming birthdays,
+// once per day or week depending on the mode. Birthdays which would be announced individually are marked in the cache.
+func sendManagerDigest(sc slack.Client, clk clock, db *DB, c *config, m *messages, mode string, userInfoMap map[string]bdInfo) error {
+	for id, info := range userInfoMap {
+		if err := db.SaveUserBDToCache(db.ManagerBucketName, info.cacheKey(id), info.Birthday); err != nil {
+			logrus.WithError(err).Errorf("Unable to save %s in manager cache for user %s", info.Kind, id)
+		}
+	}
+
+	now := clk.Now().In(c.Location)
+	period := digestPeriod(mode, now)
+	sent, err := db.GetManagerDigestPeriod(c.ManagerID)
+	if err != nil {
+		return err
+	}
+	if sent == period {
+		return nil
+	}
+
+	profiles, err := mainChannelProfiles(sc, db, c)
+	if err != nil {
+		return err
+	}
+
+	if err = sc.SendAPIMessage(c.ManagerDM, managerDigestText(m, now, profiles, db, c, digestDays(mode, c))); err != nil {
+		return errors.Wrap(err, "unable to send manager digest")
+	}
+	logrus.Infof("Sent %s digest to manager %s", mode, c.ManagerID)
+
+	return db.SaveManagerDigestPeriod(c.ManagerID, period)
+}
+
+// managerDigestText lists the birthdays in the next days with their channels
+// and the people who have no birthday data
+func managerDigestText(m *messages, now time.Time, profiles []*slack.UserProfile, db *DB, c *config, days int) string {
+	var lines []string
+	for _, bd := range upcomingBirthdays(now, profiles, db, c, days, "") {
+		line := upcomingLine(m, bd)
+		if bd.Channel == "" {
+			line = fmt.Sprintf(m.ManagerDigestNoChannel, line)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf(m.UpcomingEmpty, days))
+	}
+
+	var missing []string
+	for _, p := range profiles {
+		if _, err := parseBirthday(userBirthday(p, c), c.DateOrder, now); err != nil {
+			missing = append(missing, p.ID)
+		}
+	}
+	sort.Strings(missing)
+
+	text := fmt.Sprintf(m.ManagerDigest, days, strings.Join(lines, "\n"))
+	if len(missing) > 0 && m.ManagerDigestMissing != "" {
+		text += "\n" + fmt.Sprintf(m.ManagerDigestMissing, mentions(missing))
+	}
+	return text
+}
+
+// handleDigestCommand shows or changes the manager's announcement mode
+func handleDigestCommand(rtm slack.RTM, db *DB, c *config, msgs *messages, m slack.Message, args string) {
+	if m.User != c.ManagerID {
+		sendReply(rtm, m, fmt.Sprintf(msgs.NotAllowed, m.User))
+		return
+	}
+
+	if args == "" {
+		sendReply(rtm, m, fmt.Sprintf(msgs.DigestSaved, m.User, managerMode(db, c, m.User)))
+		return
+	}
+
+	mode, err := parseManagerMode(args)
+	if err != nil {
+		logrus.WithError(err).Infof("Unable to parse announcement mode of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.DigestUnknownMode, m.User, args))
+		return
+	}
+
+	if err = db.SaveManagerMode(m.User, mode); err != nil {
+		logrus.WithError(err).Errorf("Unable to save announcement mode of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+	logrus.Infof("User %s switched to %s announcements", m.User, mode)
+	sendReply(rtm, m, fmt.Sprintf(msgs.DigestSaved, m.User, mode))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestHandleDigestCommand(t *testing.T) {
+	_, db, c, m := newTestBot(t)
+	m.NotAllowed = "<@%s>, only manager"
+	m.DigestSaved = "<@%s>, mode: %s"
+	m.DigestUnknownMode = "<@%s>, unknown mode %q"
+
+	tests := []struct {
+		user, args string
+		want       string
+	}{
+		{"U1", "daily", "<@U1>, only manager"},
+		{"UMGR", "", "<@UMGR>, mode: individual"},
+		{"UMGR", "hourly", `<@UMGR>, unknown mode "hourly"`},
+		{"UMGR", "Weekly", "<@UMGR>, mode: weekly"},
+		{"UMGR", "", "<@UMGR>, mode: weekly"},
+	}
+	for _, tt := range tests {
+		rtm := slack.NewFakeRTM()
+		handleDigestCommand(rtm, db, c, m, slack.Message{Type: "message", User: tt.user, Conversation: "DMGR"}, tt.args)
+
+		sent := rtm.SentMessages()
+		if len(sent) != 1 || sent[0].Text != tt.want {
+			t.Errorf("digest %q by %s: expected reply %q, got %+v", tt.args, tt.user, tt.want, sent)
+		}
+	}
+}
+
+func TestParseManagerMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", managerModeIndividual, false},
+		{" Daily ", managerModeDaily, false},
+		{"weekly", managerModeWeekly, false},
+		{"monthly", "", true},
+	}
+	for _, tt := range tests {
+		mode, err := parseManagerMode(tt.in)
+		if (err != nil) != tt.wantErr || mode != tt.want {
+			t.Errorf("parseManagerMode(%q): expected %q, got %q (error %v)", tt.in, tt.want, mode, err)
+		}
+	}
+}
+
+func TestPostMonthlyDigest(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	sc.AddUser(slack.UserProfile{ID: "U3", RealName: "Cid Doe", Skype: "05.06"})
+	sc.AddUser(slack.UserProfile{ID: "U4", RealName: "Dan Roe", Skype: "01.06"})
+	sc.AddUser(slack.UserProfile{ID: "U5", RealName: "Eve Poe"})
+	sc.AddChannel(slack.Conversation{ID: "CMAIN", Name: "general"}, "UBOT", "UMGR", "U1", "U2", "U3", "U4", "U5")
+	if err := db.SaveUserBD("U5", userBD{Birthday: "2006", Private: true}); err != nil {
+		t.Fatal(err)
+	}
+	c.MonthlyDigest, c.DigestChannelID = true, "CMAIN"
+	m.MonthlyDigest = "Birthdays in %s:\n%s"
+	var err error
+	if c.Calendar, err = newWorkCalendar([]string{"saturday", "sunday"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 June 2019 is Saturday, so the digest waits for Monday
+	for _, day := range []int{1, 2} {
+		if err = postMonthlyDigest(sc, fixedClock{testDate(2019, 6, day)}, db, c, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent := sc.SentMessages("CMAIN"); len(sent) != 0 {
+		t.Fatalf("expected no digest on the weekend, got %+v", sent)
+	}
+
+	if err = postMonthlyDigest(sc, fixedClock{testDate(2019, 6, 3)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	want := "Birthdays in June 2019:\n• 01.06 Dan Roe\n• 05.06 Cid Doe"
+	if sent := sc.SentMessages("CMAIN"); len(sent) != 1 || sent[0].Text != want {
+		t.Fatalf("expected digest %q, got %+v", want, sent)
+	}
+
+	// the posted month survives the restart
+	path := db.Path()
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if db, err = openDB(&path, "manager", "channel", "", "", 0); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = initBirthdaySources(c, db, testDate(2019, 6, 4)); err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []int{3, 4, 28} {
+		if err = postMonthlyDigest(sc, fixedClock{testDate(2019, 6, day)}, db, c, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent := sc.SentMessages("CMAIN"); len(sent) != 1 {
+		t.Errorf("expected the digest to be posted once, got %+v", sent)
+	}
+
+	// no birthdays in July, nothing to post
+	if err = postMonthlyDigest(sc, fixedClock{testDate(2019, 7, 1)}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if month, err := db.GetDigestMonth(); err != nil || month != "2019-07" {
+		t.Errorf("expected July to be marked as posted, got %q (error %v)", month, err)
+	}
+	if sent := sc.SentMessages("CMAIN"); len(sent) != 1 {
+		t.Errorf("expected no digest for July, got %+v", sent)
+	}
+}
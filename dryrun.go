@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+)
+
+const commandPlan = "plan"
+
+// printPlan runs the birthday check decision logic and prints the planned announcements.
+// Nothing is sent to Slack and nothing is saved into the cache.
+func printPlan(w io.Writer, sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	now := clk.Now().In(c.Location)
+	plan, err := planBirthdays(sc, clk, db, c, time.Time{})
+	if err != nil {
+		return errors.Wrap(err, "unable to plan birthdays")
+	}
+
+	fmt.Fprintf(w, "Birthday check plan for %s\n", now.Format(time.RFC1123))
+
+	fmt.Fprintf(w, "\nManager DM (%s):\n", c.ManagerID)
+	if len(plan.Manager)+len(plan.AnnivManager)+len(plan.EventManager) == 0 {
+		fmt.Fprintln(w, "  nothing to announce")
+	}
+	for _, infoMap := range []map[string]bdInfo{plan.Manager, plan.AnnivManager, plan.EventManager} {
+		for _, id := range sortedIDs(infoMap) {
+			info := infoMap[id]
+			fmt.Fprintf(w, "  - %s of %s (%s), %d day(s) left\n", info.Kind, id, info.RealName, info.DaysLeft)
+			fmt.Fprintf(w, "    message: %s\n", managerAnnounceText(m, id, info))
+		}
+	}
+
+	fmt.Fprintln(w, "\nNew private channels:")
+	if len(plan.Channel)+len(plan.AnnivChannel)+len(plan.EventChannel) == 0 {
+		fmt.Fprintln(w, "  nothing to create")
+		return nil
+	}
+
+	members, err := sc.GetConversationMembers(c.MainChannelID)
+	if err != nil {
+		return errors.Wrap(err, "unable to get main channel members")
+	}
+	for _, infoMap := range []map[string]bdInfo{plan.Channel, plan.AnnivChannel, plan.EventChannel} {
+		for _, id := range sortedIDs(infoMap) {
+			info := infoMap[id]
+			fmt.Fprintf(w, "  - %s for %s of %s (%s), %d day(s) left\n",
+				bdChannelName(info), info.Kind, id, info.RealName, info.DaysLeft)
+			fmt.Fprintf(w, "    invite: %s\n", strings.Join(bdChannelInvitees(members, c, info.honorees(id)...), ", "))
+			fmt.Fprintf(w, "    message: %s\n", channelAnnounceText(m, c, id, info))
+		}
+	}
+
+	return nil
+}
+
+func sortedIDs(infoMap map[string]bdInfo) []string {
+	ids := make([]string, 0, len(infoMap))
+	for id := range infoMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	commandEvent = "event"
+
+	eventActionAdd    = "add"
+	eventActionRemove = "remove"
+	eventActionList   = "list"
+
+	// eventKeyPrefix separates custom events from birthdays in the cache
+	eventKeyPrefix = "event/"
+
+	// maxEventIDLength keeps the event channel names, which are '<id>-YYYY', within the Slack limit of 80 symbols
+	maxEventIDLength = 75
+)
+
+// event recurrences
+const (
+	recurrenceOnce   = "once"
+	recurrenceYearly = "yearly"
+)
+
+// supported event date formats
+var eventDateFormats = []string{"2006-01-02", "02.01.2006", "02/01/2006"}
+
+var (
+	mentionRe       = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|[^>]*)?>$`)
+	eventIDRe       = regexp.MustCompile(`[^a-z0-9]+`)
+	eventIDFormatRe = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+)
+
+// customEvent describes the team event, announced the same way as birthdays
+type customEvent struct {
+	ID           string   `json:"id" yaml:"id"` // used in commands, cache and channel names, so only a-z, 0-9 and dashes are allowed
+	Title        string   `json:"title" yaml:"title"`
+	Honorees     []string `json:"honorees,omitempty" yaml:"honorees"` // user IDs
+	Date         string   `json:"date" yaml:"date"`                   // YYYY-MM-DD, the first occurrence for recurring events
+	Recurrence   string   `json:"recurrence,omitempty" yaml:"recurrence"`
+	HighTreshold int      `json:"treshold_high,omitempty" yaml:"treshold_high"` // configured one is used if zero
+	LowTreshold  int      `json:"treshold_low,omitempty" yaml:"treshold_low"`
+}
+
+// eventID forms the event ID from its title.
+// Titles without latin letters and digits, like the cyrillic ones, get the ID from the date.
+func eventID(title, date string) string {
+	id := strings.Trim(eventIDRe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(id) > maxEventIDLength {
+		id = strings.TrimRight(id[:maxEventIDLength], "-")
+	}
+	if id == "" {
+		id = "event-" + strings.Replace(date, "-", "", -1)
+	}
+	return id
+}
+
+// parseEventDate parses the event date in any of the supported formats and returns it as YYYY-MM-DD
+func parseEventDate(s string) (string, error) {
+	for _, f := range eventDateFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", errors.Errorf("date %q doesn't match any of the formats %v", s, eventDateFormats)
+}
+
+// validate checks the event and fills its defaults
+func (e *customEvent) validate() error {
+	e.Title = strings.TrimSpace(e.Title)
+	if e.Title == "" {
+		return errors.New("event title can't be empty")
+	}
+
+	date, err := parseEventDate(e.Date)
+	if err != nil {
+		return err
+	}
+	e.Date = date
+
+	if e.ID == "" {
+		e.ID = eventID(e.Title, e.Date)
+	}
+	if !eventIDFormatRe.MatchString(e.ID) {
+		return errors.Errorf("event ID %q should consist of latin letters, digits and dashes", e.ID)
+	}
+	if len(e.ID) > maxEventIDLength {
+		return errors.Errorf("event ID %q is longer than %d symbols", e.ID, maxEventIDLength)
+	}
+
+	switch e.Recurrence = strings.ToLower(e.Recurrence); e.Recurrence {
+	case "", recurrenceOnce:
+		e.Recurrence = recurrenceOnce
+	case recurrenceYearly:
+	default:
+		return errors.Errorf("unknown recurrence %q, expected %q or %q", e.Recurrence, recurrenceOnce, recurrenceYearly)
+	}
+
+	if e.HighTreshold < e.LowTreshold {
+		return errors.New("low treshold can't be higher than the high one")
+	}
+	return nil
+}
+
+// nextOccurrence returns the amount of days left until the next occurrence of the event and its date.
+// False is returned if the event has already passed.
+func (e *customEvent) nextOccurrence(now time.Time, policy leapDayPolicy) (int, time.Time, bool) {
+	date, err := time.ParseInLocation("2006-01-02", e.Date, now.Location())
+	if err != nil {
+		return 0, date, false
+	}
+
+	if e.Recurrence == recurrenceYearly {
+		days, next, err := getUserBDInfo(now, date.Format("0201"), policy)
+		if err != nil {
+			return 0, next, false
+		}
+		if !next.Before(date) {
+			return days, next, true
+		}
+		// first occurrence is yet to come
+	}
+
+	days := daysBetween(now, date)
+	return days, date, days >= 0
+}
+
+// importEvents saves the events from the YAML or JSON file, replacing the ones with the same IDs.
+// Events removed by the manager are not imported again.
+func importEvents(path string, db *DB) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to read events file")
+	}
+
+	var events []customEvent
+	if err = yaml.Unmarshal(data, &events); err != nil {
+		return errors.Wrap(err, "unable to parse events file")
+	}
+
+	imported := 0
+	for i := range events {
+		if err = events[i].validate(); err != nil {
+			return errors.Wrapf(err, "event %d is wrong", i+1)
+		}
+
+		// events removed by the manager stay removed
+		removed, err := db.IsEventRemoved(events[i].ID)
+		if err != nil {
+			return errors.Wrapf(err, "unable to check event %s", events[i].ID)
+		}
+		if removed {
+			logrus.Infof("Event %s was removed, skipping", events[i].ID)
+			continue
+		}
+
+		if err = db.SaveEvent(events[i]); err != nil {
+			return errors.Wrapf(err, "unable to save event %s", events[i].ID)
+		}
+		imported++
+	}
+	logrus.Infof("Imported %d events from %s", imported, path)
+	return nil
+}
+
+// planEvents decides which custom events should be announced to the manager and which ones should get their channels
+func planEvents(now time.Time, db *DB, c *config, plan *announcePlan) error {
+	events, err := db.GetEvents()
+	if err != nil {
+		return errors.Wrap(err, "unable to get events")
+	}
+
+	for _, e := range events {
+		days, date, ok := e.nextOccurrence(now, c.LeapDay)
+		if !ok {
+			continue
+		}
+
+		high, low := c.EventHighTreshold, c.EventLowTreshold
+		if e.HighTreshold != 0 {
+			high, low = e.HighTreshold, e.LowTreshold
+		}
+
+		info := bdInfo{e.Title, e.ID, date.Format("02012006"), days, date, eventCustom, 0, e.Honorees}
+
+		var (
+			bucket []byte
+			target map[string]bdInfo
+		)
+		switch window := announceWindow(now, date, c); {
+		case window <= high && window > low:
+			bucket, target = db.ManagerBucketName, plan.EventManager
+		case window <= low:
+			bucket, target = db.ChannelBucketName, plan.EventChannel
+		default:
+			continue
+		}
+
+		ok, err := db.CheckUserBDInCache(bucket, info.cacheKey(e.ID), info.Birthday)
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to check event %s in cache", e.ID)
+		} else if ok {
+			logrus.Infof("Event %s is present in cache, skipping", e.ID)
+			continue
+		}
+
+		logrus.Infof("Planning event %s (%d day(s) left)", e.ID, days)
+		target[e.ID] = info
+	}
+	return nil
+}
+
+// handleEventCommand manages the custom events, only manager is allowed to do that
+func handleEventCommand(rtm slack.RTM, db *DB, c *config, msgs *messages, m slack.Message, args string) {
+	if m.User != c.ManagerID {
+		sendReply(rtm, m, fmt.Sprintf(msgs.NotAllowed, m.User))
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		sendReply(rtm, m, msgs.EventUsage)
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case eventActionAdd:
+		e, err := parseEventArgs(fields[1:])
+		if err != nil {
+			sendReply(rtm, m, fmt.Sprintf(msgs.EventAddError, m.User, err)+"\n"+msgs.EventUsage)
+			return
+		}
+		if err = db.SaveEvent(*e); err != nil {
+			logrus.WithError(err).Errorf("Unable to save event %s", e.ID)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		logrus.Infof("User %s has added event %s", m.User, e.ID)
+		sendReply(rtm, m, fmt.Sprintf(msgs.EventSaved, m.User, e.ID))
+	case eventActionRemove:
+		if len(fields) != 2 {
+			sendReply(rtm, m, msgs.EventUsage)
+			return
+		}
+		id := strings.ToLower(fields[1])
+		e, err := db.GetEvent(id)
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to get event %s", id)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		if e == nil {
+			sendReply(rtm, m, fmt.Sprintf(msgs.EventNotFound, m.User, id))
+			return
+		}
+		if err = db.DeleteEvent(id); err != nil {
+			logrus.WithError(err).Errorf("Unable to delete event %s", id)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		logrus.Infof("User %s has removed event %s", m.User, id)
+		sendReply(rtm, m, fmt.Sprintf(msgs.EventRemoved, m.User, id))
+	case eventActionList:
+		events, err := db.GetEvents()
+		if err != nil {
+			logrus.WithError(err).Errorln("Unable to get events")
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		sendReply(rtm, m, eventListText(msgs, events))
+	default:
+		sendReply(rtm, m, msgs.EventUsage)
+	}
+}
+
+// parseEventArgs parses 'date [recurrence] title [honorees]' arguments
+func parseEventArgs(fields []string) (*customEvent, error) {
+	if len(fields) < 2 {
+		return nil, errors.New("date and title are required")
+	}
+
+	e := &customEvent{Date: fields[0]}
+	fields = fields[1:]
+	if r := strings.ToLower(fields[0]); r == recurrenceOnce || r == recurrenceYearly {
+		e.Recurrence = r
+		fields = fields[1:]
+	}
+
+	var title []string
+	for _, f := range fields {
+		if parts := mentionRe.FindStringSubmatch(f); parts != nil {
+			e.Honorees = append(e.Honorees, parts[1])
+			continue
+		}
+		title = append(title, f)
+	}
+	e.Title = strings.Join(title, " ")
+
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func eventListText(msgs *messages, events []customEvent) string {
+	if len(events) == 0 {
+		return msgs.EventsEmpty
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Date < events[j].Date })
+	lines := make([]string, 0, len(events))
+	for _, e := range events {
+		line := fmt.Sprintf("• `%s` %s, %s (%s)", e.ID, e.Title, e.Date, e.Recurrence)
+		if len(e.Honorees) > 0 {
+			line += " for " + mentions(e.Honorees)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
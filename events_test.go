@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestCustomEventValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   customEvent
+		wantID  string
+		wantErr bool
+	}{
+		{"ID from title", customEvent{Title: "Team Day!", Date: "2019-06-01"}, "team-day", false},
+		{"cyrillic title", customEvent{Title: "День команды", Date: "01.06.2019"}, "event-20190601", false},
+		{"mixed title", customEvent{Title: "Тимбилдинг 2019", Date: "2019-06-01"}, "2019", false},
+		{"long title", customEvent{Title: strings.Repeat("a", 74) + " " + strings.Repeat("b", 20), Date: "2019-06-01"}, strings.Repeat("a", 74), false},
+		{"explicit ID", customEvent{ID: "farewell", Title: "Farewell", Date: "2019-05-20"}, "farewell", false},
+		{"cyrillic ID", customEvent{ID: "прощание", Title: "Farewell", Date: "2019-05-20"}, "", true},
+		{"ID with spaces", customEvent{ID: "team day", Title: "Team day", Date: "2019-06-01"}, "", true},
+		{"too long ID", customEvent{ID: strings.Repeat("a", maxEventIDLength+1), Title: "Team day", Date: "2019-06-01"}, "", true},
+		{"empty title", customEvent{Date: "2019-06-01"}, "", true},
+		{"wrong date", customEvent{Title: "Team day", Date: "June 1st"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.event
+			err := e.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && e.ID != tt.wantID {
+				t.Errorf("expected ID %q, got %q", tt.wantID, e.ID)
+			}
+			if len(bdChannelName(bdInfo{Surname: e.ID, Kind: eventCustom})) > 80 {
+				t.Errorf("channel name of event %q is too long", e.ID)
+			}
+		})
+	}
+}
+
+func TestImportEventsSkipsRemoved(t *testing.T) {
+	_, db, c, m := newTestBot(t)
+	m.EventRemoved = "<@%s>, removed %s"
+	m.EventSaved = "<@%s>, saved %s"
+	path := writeTestFile(t, "events.yaml", "- title: Team day\n  date: 2019-06-01\n- title: Farewell\n  date: 2019-05-20\n")
+
+	if err := importEvents(path, db); err != nil {
+		t.Fatal(err)
+	}
+	rtm := slack.NewFakeRTM()
+	handleEventCommand(rtm, db, c, m, slack.Message{Type: "message", User: "UMGR", Conversation: "DUMGR"}, "remove team-day")
+
+	// restart
+	if err := importEvents(path, db); err != nil {
+		t.Fatal(err)
+	}
+	if ids := eventIDs(t, db); ids != "farewell" {
+		t.Errorf("expected removed event to stay removed, got %q", ids)
+	}
+
+	// added back by the manager
+	handleEventCommand(rtm, db, c, m, slack.Message{Type: "message", User: "UMGR", Conversation: "DUMGR"}, "add 2019-06-01 Team day")
+	if err := importEvents(path, db); err != nil {
+		t.Fatal(err)
+	}
+	if ids := eventIDs(t, db); ids != "farewell,team-day" {
+		t.Errorf("expected both events, got %q", ids)
+	}
+	if removed, err := db.IsEventRemoved("team-day"); err != nil || removed {
+		t.Errorf("expected event to be no longer removed (error %v)", err)
+	}
+
+	sent := rtm.SentMessages()
+	if len(sent) != 2 || sent[0].Text != "<@UMGR>, removed team-day" || sent[1].Text != "<@UMGR>, saved team-day" {
+		t.Errorf("unexpected replies %+v", sent)
+	}
+}
+
+func eventIDs(t *testing.T, db *DB) string {
+	events, err := db.GetEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return strings.Join(ids, ",")
+}
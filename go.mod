@@ -2,7 +2,6 @@ module github.com/nezorflame/bd-reminder-bot
 
 require (
 	github.com/BurntSushi/toml v0.3.1 // indirect
-	github.com/bearbin/go-age v0.0.0-20140407072555-316d0c1e7cd1
 	github.com/coreos/bbolt v1.3.2
 	github.com/pkg/errors v0.8.1
 	github.com/sirupsen/logrus v1.4.0
@@ -10,4 +9,5 @@ require (
 	github.com/valyala/fasthttp v1.2.0
 	go.etcd.io/bbolt v1.3.2 // indirect
 	golang.org/x/net v0.0.0-20190320064053-1272bf9dcd53
+	gopkg.in/yaml.v2 v2.2.2
 )
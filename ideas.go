@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandIdea  = "idea"
+	commandIdeas = "ideas"
+	commandVote  = "vote"
+)
+
+// giftIdea describes the gift proposal in the event channel
+type giftIdea struct {
+	Text   string   `json:"text"`
+	Author string   `json:"author"`
+	Votes  []string `json:"votes,omitempty"` // user IDs
+}
+
+// vote gives the user's vote to the idea with the provided number, taking it from the other ideas
+func (col *collection) vote(userID string, number int) error {
+	if number < 1 || number > len(col.Ideas) {
+		return errors.Errorf("there's no idea #%d", number)
+	}
+
+	for i := range col.Ideas {
+		votes := col.Ideas[i].Votes[:0]
+		for _, id := range col.Ideas[i].Votes {
+			if id != userID {
+				votes = append(votes, id)
+			}
+		}
+		col.Ideas[i].Votes = votes
+	}
+	col.Ideas[number-1].Votes = append(col.Ideas[number-1].Votes, userID)
+	return nil
+}
+
+// ideaLines formats the ideas with their numbers and votes, ranked by votes if needed
+func ideaLines(ideas []giftIdea, ranked bool) string {
+	numbers := make([]int, len(ideas))
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	if ranked {
+		sort.SliceStable(numbers, func(i, j int) bool {
+			return len(ideas[numbers[i]-1].Votes) > len(ideas[numbers[j]-1].Votes)
+		})
+	}
+
+	lines := make([]string, 0, len(ideas))
+	for _, n := range numbers {
+		idea := ideas[n-1]
+		lines = append(lines, fmt.Sprintf("• #%d %s (<@%s>), %d vote(s)", n, idea.Text, idea.Author, len(idea.Votes)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// postIdeaSummaries posts the ranked ideas to the event channels on the configured day before the celebration
+func postIdeaSummaries(sc slack.Client, clk clock, db *DB, c *config, m *messages) error {
+	if c.IdeasSummaryDays == 0 {
+		return nil
+	}
+
+	collections, err := db.GetCollections()
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now().In(c.Location)
+	for _, col := range collections {
+		if col.Closed || col.IdeasSummarized || len(col.Ideas) == 0 {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", col.Date, c.Location)
+		if err != nil {
+			logrus.WithError(err).Errorf("Wrong date of collection in channel %s", col.ChannelID)
+			continue
+		}
+		if days := daysBetween(now, date); days < 0 || days > c.IdeasSummaryDays {
+			continue
+		}
+
+		// mark first, so that the summary is posted once and has the ideas proposed meanwhile
+		err = db.UpdateCollection(col.ChannelID, func(current *collection) error {
+			if current.Closed || current.IdeasSummarized {
+				return errCollectionUnchanged
+			}
+			current.IdeasSummarized = true
+			col = *current
+			return nil
+		})
+		if err == errCollectionUnchanged {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Unable to save collection in channel %s", col.ChannelID)
+			continue
+		}
+
+		if err = sc.SendAPIMessage(col.ChannelID, fmt.Sprintf(m.IdeasSummary, col.Title, ideaLines(col.Ideas, true))); err != nil {
+			logrus.WithError(err).Errorf("Unable to post ideas summary to channel %s", col.ChannelID)
+
+			// let the next check try again
+			err = db.UpdateCollection(col.ChannelID, func(current *collection) error {
+				current.IdeasSummarized = false
+				return nil
+			})
+			if err != nil {
+				logrus.WithError(err).Errorf("Unable to save collection in channel %s", col.ChannelID)
+			}
+			continue
+		}
+		logrus.Infof("Posted ideas summary to channel %s", col.ChannelID)
+	}
+	return nil
+}
+
+// handleIdea saves the gift idea proposed by the user
+func handleIdea(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, args string) {
+	if args == "" {
+		sendReply(rtm, m, fmt.Sprintf(msgs.IdeaUsage, m.User, "idea can't be empty"))
+		return
+	}
+
+	var number int
+	ok := updateChannelCollection(rtm, db, msgs, m, msgs.IdeaUsage, func(col *collection) error {
+		col.Ideas = append(col.Ideas, giftIdea{Text: args, Author: m.User})
+		number = len(col.Ideas)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	logrus.Infof("User %s proposed idea #%d in channel %s", m.User, number, m.Conversation)
+	sendReply(rtm, m, fmt.Sprintf(msgs.IdeaSaved, m.User, number))
+}
+
+// handleIdeas lists the gift ideas of the channel
+func handleIdeas(rtm slack.RTM, db *DB, msgs *messages, m slack.Message) {
+	col := channelCollection(rtm, db, msgs, m)
+	if col == nil {
+		return
+	}
+
+	if len(col.Ideas) == 0 {
+		sendReply(rtm, m, fmt.Sprintf(msgs.IdeasEmpty, m.User))
+		return
+	}
+	sendReply(rtm, m, fmt.Sprintf(msgs.IdeasList, col.Title, ideaLines(col.Ideas, false)))
+}
+
+// handleVote saves the user's vote, each user has a single vote
+func handleVote(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, args string) {
+	number, err := strconv.Atoi(strings.TrimPrefix(args, "#"))
+	if err != nil {
+		sendReply(rtm, m, fmt.Sprintf(msgs.VoteUsage, m.User, fmt.Sprintf("%q is not an idea number", args)))
+		return
+	}
+
+	ok := updateChannelCollection(rtm, db, msgs, m, msgs.VoteUsage, func(col *collection) error {
+		return col.vote(m.User, number)
+	})
+	if !ok {
+		return
+	}
+
+	logrus.Infof("User %s voted for idea #%d in channel %s", m.User, number, m.Conversation)
+	sendReply(rtm, m, fmt.Sprintf(msgs.VoteSaved, m.User, number))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestConcurrentIdeasAndVotes(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	m.IdeaSaved, m.VoteSaved, m.IdeasSummary = "<@%s>, idea #%d", "<@%s>, vote #%d", "Ideas for %s\n%s"
+	m.CollectionNotFound = "<@%s>, no collection"
+	c.IdeasSummaryDays = 3
+
+	info := bdInfo{RealName: "Ann Lee", Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := openCollection(db, c, "CBD", "UHERO", info, nil); err != nil {
+		t.Fatal(err)
+	}
+	handleIdea(slack.NewFakeRTM(), db, m, slack.Message{User: "U1", Conversation: "CBD"}, "Board game")
+
+	// summary which failed to be posted is retried on the next check
+	now := time.Date(2019, 2, 26, 10, 0, 0, 0, time.UTC)
+	if err := postIdeaSummaries(sc, fixedClock{now}, db, c, m); err != nil {
+		t.Fatal(err)
+	}
+	if col, _ := db.GetCollection("CBD"); col == nil || col.IdeasSummarized {
+		t.Fatalf("expected the summary to be retried, got %+v", col)
+	}
+	sc.AddChannel(slack.Conversation{ID: "CBD", Name: "lee-bd-2019"}, "UBOT", "UMGR", "U2")
+
+	// ideas and votes come while the watcher posts the summary
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			handleIdea(slack.NewFakeRTM(), db, m, slack.Message{User: fmt.Sprintf("UA%d", i), Conversation: "CBD"}, "Idea "+strconv.Itoa(i))
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			handleVote(slack.NewFakeRTM(), db, m, slack.Message{User: fmt.Sprintf("UV%d", i), Conversation: "CBD"}, "#1")
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := postIdeaSummaries(sc, fixedClock{now}, db, c, m); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	col, err := db.GetCollection("CBD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(col.Ideas) != 11 {
+		t.Errorf("expected 11 ideas, got %d", len(col.Ideas))
+	}
+	if votes := len(col.Ideas[0].Votes); votes != 10 {
+		t.Errorf("expected 10 votes for the first idea, got %d", votes)
+	}
+	if !col.IdeasSummarized {
+		t.Error("expected the summary to be marked as posted")
+	}
+	if len(sc.Messages) != 1 {
+		t.Errorf("expected a single summary, got %d", len(sc.Messages))
+	}
+}
+
+func TestIdeaAndVoteUsage(t *testing.T) {
+	_, db, c, m := newTestBot(t)
+	m.IdeaSaved, m.VoteSaved = "<@%s>, idea #%d", "<@%s>, vote #%d"
+	m.IdeaUsage, m.VoteUsage = "<@%s>, %s. Try `idea Board game`", "<@%s>, %s. Try `vote 2`"
+	info := bdInfo{RealName: "Ann Lee", Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := openCollection(db, c, "CBD", "U1", info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rtm := slack.NewFakeRTM()
+	msg := slack.Message{User: "U2", Conversation: "CBD"}
+	handleIdea(rtm, db, m, msg, "")
+	handleIdea(rtm, db, m, msg, "Board game")
+	handleVote(rtm, db, m, msg, "first")
+	handleVote(rtm, db, m, msg, "#2")
+	handleVote(rtm, db, m, msg, "#1")
+
+	want := []string{
+		"<@U2>, idea can't be empty. Try `idea Board game`",
+		"<@U2>, idea #1",
+		`<@U2>, "first" is not an idea number. Try ` + "`vote 2`",
+		"<@U2>, there's no idea #2. Try `vote 2`",
+		"<@U2>, vote #1",
+	}
+	sent := rtm.SentMessages()
+	if len(sent) != len(want) {
+		t.Fatalf("expected %d replies, got %+v", len(want), sent)
+	}
+	for i := range want {
+		if sent[i].Text != want[i] {
+			t.Errorf("expected reply %q, got %q", want[i], sent[i].Text)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// leapDayPolicy describes when 29th of February birthdays are celebrated in non-leap years
+type leapDayPolicy int
+
+// leap day policies
+const (
+	leapDayFeb28 leapDayPolicy = iota
+	leapDayMar1
+)
+
+// leap day policy names, used in config
+const (
+	leapDayFeb28Name = "feb28"
+	leapDayMar1Name  = "mar1"
+)
+
+// parseLeapDayPolicy parses the leap day policy config value
+func parseLeapDayPolicy(s string) (leapDayPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", leapDayFeb28Name:
+		return leapDayFeb28, nil
+	case leapDayMar1Name:
+		return leapDayMar1, nil
+	default:
+		return leapDayFeb28, errors.Errorf("unknown leap day policy %q, expected %q or %q", s, leapDayFeb28Name, leapDayMar1Name)
+	}
+}
+
+// celebrationDate returns the start of the day when the birthday is celebrated in the provided year
+func celebrationDate(year int, month time.Month, day int, policy leapDayPolicy, loc *time.Location) time.Time {
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		if policy == leapDayMar1 {
+			month, day = time.March, 1
+		} else {
+			day = 28
+		}
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
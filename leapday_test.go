@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCelebrationDate(t *testing.T) {
+	tests := []struct {
+		year   int
+		policy leapDayPolicy
+		want   string
+	}{
+		{2019, leapDayFeb28, "2019-02-28"},
+		{2019, leapDayMar1, "2019-03-01"},
+		{2020, leapDayFeb28, "2020-02-29"},
+		{2020, leapDayMar1, "2020-02-29"},
+		{2000, leapDayMar1, "2000-02-29"},
+		{2100, leapDayFeb28, "2100-02-28"},
+		{2100, leapDayMar1, "2100-03-01"},
+	}
+	for _, tt := range tests {
+		got := celebrationDate(tt.year, time.February, 29, tt.policy, time.UTC).Format("2006-01-02")
+		if got != tt.want {
+			t.Errorf("celebrationDate(%d, %d): expected %s, got %s", tt.year, tt.policy, tt.want, got)
+		}
+	}
+}
+
+func TestGetUserBDInfoLeapDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		policy   leapDayPolicy
+		wantDays int
+		wantDate string
+	}{
+		{"non-leap year feb28, today", testDate(2019, 2, 28), leapDayFeb28, 0, "2019-02-28"},
+		{"non-leap year mar1, day before", testDate(2019, 2, 28), leapDayMar1, 1, "2019-03-01"},
+		{"non-leap year feb28, passed", testDate(2019, 3, 1), leapDayFeb28, 365, "2020-02-29"},
+		{"non-leap year mar1, today", testDate(2019, 3, 1), leapDayMar1, 0, "2019-03-01"},
+		{"into non-leap year feb28", testDate(2018, 12, 31), leapDayFeb28, 59, "2019-02-28"},
+		{"into non-leap year mar1", testDate(2018, 12, 31), leapDayMar1, 60, "2019-03-01"},
+		{"into leap year feb28", testDate(2019, 12, 31), leapDayFeb28, 60, "2020-02-29"},
+		{"into leap year mar1", testDate(2019, 12, 31), leapDayMar1, 60, "2020-02-29"},
+		{"leap year feb28, today", testDate(2020, 2, 29), leapDayFeb28, 0, "2020-02-29"},
+		{"leap year mar1, today", testDate(2020, 2, 29), leapDayMar1, 0, "2020-02-29"},
+		{"leap year feb28, passed", testDate(2020, 3, 1), leapDayFeb28, 364, "2021-02-28"},
+		{"leap year mar1, passed", testDate(2020, 3, 1), leapDayMar1, 365, "2021-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, bdDate, err := getUserBDInfo(tt.now, "2902", tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if days != tt.wantDays || bdDate.Format("2006-01-02") != tt.wantDate {
+				t.Errorf("expected %d day(s) until %s, got %d until %s", tt.wantDays, tt.wantDate, days, bdDate.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+// testDate returns the time at 10:00 UTC of the provided day
+func testDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 10, 0, 0, 0, time.UTC)
+}
@@ -8,8 +8,10 @@ package main // import "github.com/nezorflame/bd-reminder-bot"
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -25,8 +27,14 @@ func main() {
 	debugPtr := flag.Bool("debug", false, "debug level for logs")
 	configPtr := flag.String("config", "config", "config file name")
 	dbPtr := flag.String("db", "./bolt.db", "BoltDB file location")
+	simulatePtr := flag.String("simulate-date", "", "print the planned announcements as if it was the provided date (YYYY-MM-DD or YYYY-MM-DDTHH:MM) and exit")
+	flag.StringVar(simulatePtr, "now", "", "shorthand for simulate-date")
+	dryRunPtr := flag.Bool("dry-run", false, "print the planned announcements or the channels to clean up without touching Slack or the cache and exit")
 	flag.Parse()
 
+	// 'plan' subcommand is the same as dry run
+	dryRun := *dryRunPtr || flag.Arg(0) == commandPlan
+
 	// set log level
 	if *debugPtr {
 		logrus.SetLevel(logrus.DebugLevel)
@@ -43,20 +51,67 @@ func main() {
 	}
 
 	// parse config
-	mBucket, cBucket, botToken, c, m, err := parseConfig()
+	mBucket, cBucket, gBucket, uBucket, botToken, c, m, err := parseConfig()
 	if err != nil {
 		logrus.WithError(err).Fatalf("Unable to init config")
 	}
 
 	// connect to BoltDB
-	db, err := openDB(dbPtr, mBucket, cBucket, DefaultDBTimeout)
+	db, err := openDB(dbPtr, mBucket, cBucket, gBucket, uBucket, DefaultDBTimeout)
 	if err != nil {
 		logrus.WithError(err).Fatalf("Unable to open DB")
 	}
 	defer db.Close()
 
+	// the simulated date is parsed early, so that the dates in the sources are validated against it
+	var clk clock = realClock{}
+	if *simulatePtr != "" {
+		now, err := parseSimulatedDate(*simulatePtr, c.Location, c.WorkdayStart)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Unable to parse simulated date")
+		}
+		clk = fixedClock{now}
+	}
+
+	// init birthday sources
+	if err = initBirthdaySources(c, db, clk.Now().In(c.Location)); err != nil {
+		logrus.WithError(err).Fatalf("Unable to init birthday sources")
+	}
+
 	// connect to Slack
-	wsConfig, botUID, err := slack.InitWSConfig(botToken)
+	sc := slack.NewHTTPClient(c.APIURL, botToken, c.LegacyToken)
+	if err = resolveBDField(sc, c); err != nil {
+		logrus.WithError(err).Fatalf("Unable to find birthday profile field")
+	}
+	if err = initAnniversaries(sc, c, clk.Now().In(c.Location)); err != nil {
+		logrus.WithError(err).Fatalf("Unable to init work anniversaries")
+	}
+
+	// archive the leftover channels, if needed
+	if flag.Arg(0) == commandCleanup {
+		if err = cleanupChannels(sc, realClock{}, db, c, m, dryRun); err != nil {
+			logrus.WithError(err).Fatalf("Cleanup failed")
+		}
+		return
+	}
+
+	// simulate the date or plan, if needed.
+	// Simulation is always a dry run, so that rehearsing a date doesn't touch Slack or the cache.
+	if *simulatePtr != "" || dryRun {
+		if err = printPlan(os.Stdout, sc, clk, db, c, m); err != nil {
+			logrus.WithError(err).Fatalf("Birthday check failed")
+		}
+		return
+	}
+
+	// import custom events, dry runs only plan the events which were imported before
+	if c.EventsFile != "" {
+		if err = importEvents(c.EventsFile, db); err != nil {
+			logrus.WithError(err).Fatalf("Unable to import events")
+		}
+	}
+
+	botUID, err := sc.InitRTM()
 	if err != nil {
 		logrus.WithError(err).Fatalf("Unable to get Slack WS config")
 	}
@@ -81,19 +136,19 @@ func main() {
 			}
 
 			// dial websocket
-			wsConn, err := slack.DialWS(wsConfig)
+			rtm, err := sc.DialRTM()
 			if err != nil {
 				errCount++
 				logrus.WithError(err).WithField("try", errCount).Errorf("Unable to connect to Slack's websocket, retrying")
 				continue
 			}
-			defer wsConn.Close() // not interested in this error, so skipping
-			errCount = 0         // resetting the counter
+			defer rtm.Close() // not interested in this error, so skipping
+			errCount = 0      // resetting the counter
 
 			// launch message watcher
-			if err := msgWatcher(ctx, wsConn, c, m); err != nil {
+			if err := msgWatcher(ctx, rtm, sc, realClock{}, db, c, m); err != nil {
 				errCount++
-				wsConn.Close() // not interested in this error, so skipping
+				rtm.Close() // not interested in this error, so skipping
 				logrus.WithError(err).WithField("try", errCount).Warnln("Message watcher failed, trying to reconnect")
 				continue
 			}
@@ -105,12 +160,23 @@ func main() {
 
 	// launch birthday watcher
 	go func() {
-		if err := bdWatcher(ctx, db, c, m); err != nil {
+		if err := bdWatcher(ctx, sc, realClock{}, db, c, m); err != nil {
 			logrus.WithError(err).Errorln("Birthday watcher failed")
 		}
 		cancel()
 		wg.Done()
 	}()
+	// launch congratulations watcher
+	if c.CongratsDM || c.CongratsChannel {
+		wg.Add(1)
+		go func() {
+			if err := congratsWatcher(ctx, sc, realClock{}, db, c, m); err != nil {
+				logrus.WithError(err).Errorln("Congratulations watcher failed")
+			}
+			cancel()
+			wg.Done()
+		}()
+	}
 	logrus.Infoln("Bot is ready with user ID", botUID)
 
 	// watch the OS signals
@@ -131,7 +197,60 @@ func main() {
 	}
 }
 
-func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err error) {
+// defaultMessages are used for the messages added after the first release, so that the older configs keep working
+var defaultMessages = map[string]string{
+	"personal_saved":  "<@%s>, got it, I'll remember your birthday! :memo:",
+	"personal_forgot": "<@%s>, done, I've forgotten your birthday :zipper_mouth_face:",
+	"not_allowed":     "<@%s>, sorry, but only team manager is allowed to do that :)",
+
+	"event_manager_announce": "Event '%s' %s is in %d days!",
+	"event_channel_announce": "Event '%s' %s is at %s! Please, send money to <@%s> to participate",
+	"event_saved":            "<@%s>, event `%s` is saved :memo:",
+	"event_removed":          "<@%s>, event `%s` is removed",
+	"event_not_found":        "<@%s>, there's no event `%s`",
+	"event_add_error":        "<@%s>, unable to add the event: %s",
+	"event_usage":            "Usage: `event add 2019-06-01 [yearly] Team day [@honoree ...]`, `event remove team-day` or `event list`",
+	"events_empty":           "There are no events",
+
+	"upcoming_header": "Birthdays in the next %d days:",
+	"upcoming_empty":  "No birthdays in the next %d days :sleeping:",
+	"upcoming_line":   "• %s %s, in %d day(s)",
+	"upcoming_today":  "• %s %s, today",
+	"upcoming_usage":  "<@%s>, try something like `upcoming 14 days`, up to %d days",
+
+	"manager_digest":            "Birthdays in the next %d days:\n%s",
+	"manager_digest_no_channel": "%s, no channel yet",
+	"digest_saved":              "<@%s>, birthday announcements mode: %s",
+	"digest_unknown_mode":       "<@%s>, unknown mode %q, try `digest individual`, `digest daily` or `digest weekly`",
+
+	"collection_paid":      "<@%s>, thank you! Your contribution is saved :moneybag:",
+	"collection_status":    "Collection for %s: %s from %d people\n%s",
+	"collection_closed":    "Collection for %s is closed: %s from %d people\n%s",
+	"collection_not_found": "<@%s>, there's no open collection in this channel",
+	"collection_skipped":   "<@%s>, got it, no reminders for you",
+	"collection_reminder":  "Hi <@%s>! Just a reminder about the collection for %s, there are %d days left: <#%s>",
+	"collection_usage":     "<@%s>, %s. Use `paid [amount]` to mark your contribution or `skip` if you won't contribute",
+	"amount_error":         "<@%s>, %s. Try something like `paid 500`, or just `paid`",
+
+	"idea_saved":    "<@%s>, idea #%d is saved :bulb:",
+	"ideas_list":    "Gift ideas for %s:\n%s",
+	"ideas_empty":   "<@%s>, there are no ideas yet, propose one with `idea`",
+	"ideas_summary": "Gift ideas for %s, ranked by votes :trophy:\n%s",
+	"vote_saved":    "<@%s>, your vote for idea #%d is saved",
+	"idea_usage":    "<@%s>, %s. Propose the gift idea like this: `idea Board game`",
+	"vote_usage":    "<@%s>, %s. Vote with the idea number from the `ideas` list: `vote 2`",
+
+	"wish_saved":      "<@%s>, wish #%d is saved :gift:",
+	"wish_removed":    "<@%s>, wish #%d is removed",
+	"wish_dm_only":    "<@%s>, wishlists are secret, please, send me `wish` commands in DM :shushing_face:",
+	"wish_usage":      "<@%s>, try `wish add Coffee grinder`, `wish list` or `wish remove 2`",
+	"wish_not_found":  "<@%s>, there's no wish #%s in your wishlist, check the numbers with `wish list`",
+	"wishlist_list":   "<@%s>, your wishlist:\n%s",
+	"wishlist_empty":  "<@%s>, your wishlist is empty, add something with `wish add`",
+	"wishlist_shared": "Here's what %s wishes for :gift:\n%s",
+}
+
+func parseConfig() (mBucket, cBucket, gBucket, uBucket, bToken string, c *config, m *messages, err error) {
 	// base settings
 	if mBucket = viper.GetString("manager_bucket"); mBucket == "" {
 		err = errors.New("manager_bucket can't be empty")
@@ -143,6 +262,9 @@ func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err
 		return
 	}
 
+	gBucket = viper.GetString("congrats_bucket") // can be empty, default one is used then
+	uBucket = viper.GetString("user_bucket")     // can be empty, default one is used then
+
 	// init the config variables
 	c = &config{}
 
@@ -162,6 +284,79 @@ func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err
 		return
 	}
 
+	// init schedules, hourly checks during the workday are used by default
+	defaultSchedule := fmt.Sprintf("0 %d-%d * * *", c.WorkdayStart, c.WorkdayEnd)
+	scheduleSection := viper.Sub("schedule")
+	if scheduleSection == nil {
+		scheduleSection = viper.New() // section is optional
+	}
+	scheduleSection.SetDefault("manager", defaultSchedule)
+	scheduleSection.SetDefault("channel", defaultSchedule)
+
+	if c.ManagerSchedule, err = parseSchedule(scheduleSection.GetString("manager")); err != nil {
+		err = errors.Wrap(err, "schedule.manager is wrong")
+		return
+	}
+
+	if c.ChannelSchedule, err = parseSchedule(scheduleSection.GetString("channel")); err != nil {
+		err = errors.Wrap(err, "schedule.channel is wrong")
+		return
+	}
+
+	// init working calendar
+	calendarSection := viper.Sub("calendar")
+	if calendarSection == nil {
+		calendarSection = viper.New() // section is optional
+	}
+	calendarSection.SetDefault("weekend", []string{"saturday", "sunday"})
+
+	if c.Calendar, err = newWorkCalendar(
+		calendarSection.GetStringSlice("weekend"), calendarSection.GetString("holidays_file"),
+	); err != nil {
+		err = errors.Wrap(err, "calendar is wrong")
+		return
+	}
+	c.ShiftAnnouncements = calendarSection.GetBool("shift_announcements")
+	c.WorkdayTresholds = calendarSection.GetBool("workday_tresholds")
+
+	// init work anniversaries settings
+	annivSection := viper.Sub("anniversaries")
+	if annivSection == nil {
+		annivSection = viper.New() // section is optional
+	}
+	if c.Anniversaries = annivSection.GetBool("enabled"); c.Anniversaries {
+		c.AnnivSourceNames = annivSection.GetStringSlice("sources")
+		c.AnnivField = annivSection.GetString("field")
+		c.AnnivRosterFile = annivSection.GetString("roster_file")
+
+		if c.AnnivHighTreshold = annivSection.GetInt("treshold_high"); c.AnnivHighTreshold == 0 {
+			err = errors.New("anniversaries.treshold_high can't be zero")
+			return
+		}
+
+		if c.AnnivLowTreshold = annivSection.GetInt("treshold_low"); c.AnnivLowTreshold == 0 {
+			err = errors.New("anniversaries.treshold_low can't be zero")
+			return
+		}
+		if c.AnnivHighTreshold < c.AnnivLowTreshold {
+			err = errors.New("anniversaries.treshold_low can't be higher than anniversaries.treshold_high")
+			return
+		}
+	}
+
+	// init personal congratulations
+	congratsSection := viper.Sub("congrats")
+	if congratsSection == nil {
+		congratsSection = viper.New() // section is optional
+	}
+	congratsSection.SetDefault("hour", c.WorkdayStart)
+	if c.CongratsHour = congratsSection.GetInt("hour"); c.CongratsHour < 0 || c.CongratsHour > 23 {
+		err = errors.New("congrats.hour should be between 0 and 23")
+		return
+	}
+	c.CongratsDM = congratsSection.GetBool("dm")
+	c.CongratsChannel = congratsSection.GetBool("channel")
+
 	// init Slack variables
 	slackSection := viper.Sub("slack")
 
@@ -170,6 +365,8 @@ func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err
 		return
 	}
 
+	c.APIURL = slackSection.GetString("api_url") // can be empty, Slack's one is used by default
+
 	if c.LegacyToken = slackSection.GetString("legacy_token"); c.LegacyToken == "" {
 		err = errors.New("legacy_token can't be empty")
 		return
@@ -199,13 +396,91 @@ func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err
 		return
 	}
 
+	c.BDField = slackSection.GetString("bd_field") // can be empty, Skype field is used then
+
 	if c.Blacklist = slackSection.GetStringSlice("blacklist"); len(c.Blacklist) == 0 {
 		logrus.Warnln("blacklist is empty")
 	}
 
+	// init custom events settings, birthday ones are used by default
+	eventSection := viper.Sub("events")
+	if eventSection == nil {
+		eventSection = viper.New() // section is optional
+	}
+	eventSection.SetDefault("treshold_high", c.BDHighTreshold)
+	eventSection.SetDefault("treshold_low", c.BDLowTreshold)
+	c.EventHighTreshold = eventSection.GetInt("treshold_high")
+	c.EventLowTreshold = eventSection.GetInt("treshold_low")
+	if c.EventHighTreshold < c.EventLowTreshold {
+		err = errors.New("events.treshold_low can't be higher than events.treshold_high")
+		return
+	}
+	c.EventsFile = eventSection.GetString("file")
+
+	// init monthly digest settings
+	digestSection := viper.Sub("digest")
+	if digestSection == nil {
+		digestSection = viper.New() // section is optional
+	}
+	c.MonthlyDigest = digestSection.GetBool("monthly")
+	if c.DigestChannelID = digestSection.GetString("channel_id"); c.DigestChannelID == "" {
+		c.DigestChannelID = c.MainChannelID
+	}
+	if c.ManagerMode, err = parseManagerMode(digestSection.GetString("manager")); err != nil {
+		err = errors.Wrap(err, "digest.manager is wrong")
+		return
+	}
+
+	// init money collection settings
+	collectionSection := viper.Sub("collection")
+	if collectionSection == nil {
+		collectionSection = viper.New() // section is optional
+	}
+	for _, d := range collectionSection.GetStringSlice("reminder_days") {
+		var days int
+		if days, err = strconv.Atoi(d); err != nil || days < 1 {
+			err = errors.Errorf("collection.reminder_days: %q is not a positive amount of days", d)
+			return
+		}
+		c.ReminderDays = append(c.ReminderDays, days)
+	}
+	if c.IdeasSummaryDays = collectionSection.GetInt("ideas_summary_days"); c.IdeasSummaryDays < 0 {
+		err = errors.New("collection.ideas_summary_days can't be negative")
+		return
+	}
+
+	// init event channels settings
+	channelsSection := viper.Sub("channels")
+	if channelsSection == nil {
+		channelsSection = viper.New() // section is optional
+	}
+	c.ArchiveChannels = channelsSection.GetBool("archive")
+	if c.ArchiveGraceDays = channelsSection.GetInt("grace_days"); c.ArchiveGraceDays < 0 {
+		err = errors.New("channels.grace_days can't be negative")
+		return
+	}
+
+	// init birthday sources settings
+	bdSection := viper.Sub("birthdays")
+	if bdSection == nil {
+		bdSection = viper.New() // section is optional
+	}
+	c.BDSourceNames = bdSection.GetStringSlice("sources")
+	c.RosterFile = bdSection.GetString("roster_file")
+	c.HRFile = bdSection.GetString("hr_file")
+	if c.DateOrder, err = parseDateOrder(bdSection.GetString("date_order")); err != nil {
+		return
+	}
+	if c.LeapDay, err = parseLeapDayPolicy(bdSection.GetString("leap_day")); err != nil {
+		return
+	}
+
 	// init the message texts
 	m = &messages{}
 	msgSection := viper.Sub("messages")
+	for key, text := range defaultMessages {
+		msgSection.SetDefault(key, text)
+	}
 
 	m.ShutdownAnnounce = msgSection.GetString("shutdown_announce") // can be empty, why not
 
@@ -244,5 +519,261 @@ func parseConfig() (mBucket, cBucket, bToken string, c *config, m *messages, err
 		return
 	}
 
+	if m.PersonalSaved = msgSection.GetString("personal_saved"); m.PersonalSaved == "" {
+		err = errors.New("messages.personal_saved can't be empty")
+		return
+	}
+
+	if m.PersonalForgot = msgSection.GetString("personal_forgot"); m.PersonalForgot == "" {
+		err = errors.New("messages.personal_forgot can't be empty")
+		return
+	}
+
+	if m.NotAllowed = msgSection.GetString("not_allowed"); m.NotAllowed == "" {
+		err = errors.New("messages.not_allowed can't be empty")
+		return
+	}
+
+	if c.Anniversaries {
+		if m.AnnivManagerAnnounce = msgSection.GetString("anniversary_manager_announce"); m.AnnivManagerAnnounce == "" {
+			err = errors.New("messages.anniversary_manager_announce can't be empty")
+			return
+		}
+
+		if m.AnnivChannelAnnounce = msgSection.GetString("anniversary_channel_announce"); m.AnnivChannelAnnounce == "" {
+			err = errors.New("messages.anniversary_channel_announce can't be empty")
+			return
+		}
+
+		if m.AnnivSaved = msgSection.GetString("anniversary_saved"); m.AnnivSaved == "" {
+			err = errors.New("messages.anniversary_saved can't be empty")
+			return
+		}
+	}
+
+	if m.EventManagerAnnounce = msgSection.GetString("event_manager_announce"); m.EventManagerAnnounce == "" {
+		err = errors.New("messages.event_manager_announce can't be empty")
+		return
+	}
+
+	if m.EventChannelAnnounce = msgSection.GetString("event_channel_announce"); m.EventChannelAnnounce == "" {
+		err = errors.New("messages.event_channel_announce can't be empty")
+		return
+	}
+
+	if m.EventSaved = msgSection.GetString("event_saved"); m.EventSaved == "" {
+		err = errors.New("messages.event_saved can't be empty")
+		return
+	}
+
+	if m.EventRemoved = msgSection.GetString("event_removed"); m.EventRemoved == "" {
+		err = errors.New("messages.event_removed can't be empty")
+		return
+	}
+
+	if m.EventNotFound = msgSection.GetString("event_not_found"); m.EventNotFound == "" {
+		err = errors.New("messages.event_not_found can't be empty")
+		return
+	}
+
+	if m.EventAddError = msgSection.GetString("event_add_error"); m.EventAddError == "" {
+		err = errors.New("messages.event_add_error can't be empty")
+		return
+	}
+
+	if m.EventUsage = msgSection.GetString("event_usage"); m.EventUsage == "" {
+		err = errors.New("messages.event_usage can't be empty")
+		return
+	}
+
+	if m.EventsEmpty = msgSection.GetString("events_empty"); m.EventsEmpty == "" {
+		err = errors.New("messages.events_empty can't be empty")
+		return
+	}
+
+	if m.UpcomingHeader = msgSection.GetString("upcoming_header"); m.UpcomingHeader == "" {
+		err = errors.New("messages.upcoming_header can't be empty")
+		return
+	}
+
+	if m.UpcomingEmpty = msgSection.GetString("upcoming_empty"); m.UpcomingEmpty == "" {
+		err = errors.New("messages.upcoming_empty can't be empty")
+		return
+	}
+
+	if m.UpcomingLine = msgSection.GetString("upcoming_line"); m.UpcomingLine == "" {
+		err = errors.New("messages.upcoming_line can't be empty")
+		return
+	}
+
+	if m.UpcomingToday = msgSection.GetString("upcoming_today"); m.UpcomingToday == "" {
+		err = errors.New("messages.upcoming_today can't be empty")
+		return
+	}
+
+	if m.UpcomingUsage = msgSection.GetString("upcoming_usage"); m.UpcomingUsage == "" {
+		err = errors.New("messages.upcoming_usage can't be empty")
+		return
+	}
+
+	if m.MonthlyDigest = msgSection.GetString("monthly_digest"); m.MonthlyDigest == "" && c.MonthlyDigest {
+		err = errors.New("messages.monthly_digest can't be empty")
+		return
+	}
+
+	if m.ManagerDigest = msgSection.GetString("manager_digest"); m.ManagerDigest == "" {
+		err = errors.New("messages.manager_digest can't be empty")
+		return
+	}
+
+	m.ManagerDigestMissing = msgSection.GetString("manager_digest_missing") // can be empty, missing data isn't listed then
+
+	if m.ManagerDigestNoChannel = msgSection.GetString("manager_digest_no_channel"); m.ManagerDigestNoChannel == "" {
+		err = errors.New("messages.manager_digest_no_channel can't be empty")
+		return
+	}
+
+	if m.DigestSaved = msgSection.GetString("digest_saved"); m.DigestSaved == "" {
+		err = errors.New("messages.digest_saved can't be empty")
+		return
+	}
+
+	if m.DigestUnknownMode = msgSection.GetString("digest_unknown_mode"); m.DigestUnknownMode == "" {
+		err = errors.New("messages.digest_unknown_mode can't be empty")
+		return
+	}
+
+	if m.CollectionPaid = msgSection.GetString("collection_paid"); m.CollectionPaid == "" {
+		err = errors.New("messages.collection_paid can't be empty")
+		return
+	}
+
+	if m.CollectionStatus = msgSection.GetString("collection_status"); m.CollectionStatus == "" {
+		err = errors.New("messages.collection_status can't be empty")
+		return
+	}
+
+	if m.CollectionClosed = msgSection.GetString("collection_closed"); m.CollectionClosed == "" {
+		err = errors.New("messages.collection_closed can't be empty")
+		return
+	}
+
+	if m.CollectionNotFound = msgSection.GetString("collection_not_found"); m.CollectionNotFound == "" {
+		err = errors.New("messages.collection_not_found can't be empty")
+		return
+	}
+
+	if m.CollectionSkipped = msgSection.GetString("collection_skipped"); m.CollectionSkipped == "" {
+		err = errors.New("messages.collection_skipped can't be empty")
+		return
+	}
+
+	if m.CollectionReminder = msgSection.GetString("collection_reminder"); m.CollectionReminder == "" && len(c.ReminderDays) > 0 {
+		err = errors.New("messages.collection_reminder can't be empty")
+		return
+	}
+
+	if m.CollectionUsage = msgSection.GetString("collection_usage"); m.CollectionUsage == "" {
+		err = errors.New("messages.collection_usage can't be empty")
+		return
+	}
+
+	if m.AmountError = msgSection.GetString("amount_error"); m.AmountError == "" {
+		err = errors.New("messages.amount_error can't be empty")
+		return
+	}
+
+	if m.IdeaSaved = msgSection.GetString("idea_saved"); m.IdeaSaved == "" {
+		err = errors.New("messages.idea_saved can't be empty")
+		return
+	}
+
+	if m.IdeasList = msgSection.GetString("ideas_list"); m.IdeasList == "" {
+		err = errors.New("messages.ideas_list can't be empty")
+		return
+	}
+
+	if m.IdeasEmpty = msgSection.GetString("ideas_empty"); m.IdeasEmpty == "" {
+		err = errors.New("messages.ideas_empty can't be empty")
+		return
+	}
+
+	if m.IdeasSummary = msgSection.GetString("ideas_summary"); m.IdeasSummary == "" && c.IdeasSummaryDays > 0 {
+		err = errors.New("messages.ideas_summary can't be empty")
+		return
+	}
+
+	if m.VoteSaved = msgSection.GetString("vote_saved"); m.VoteSaved == "" {
+		err = errors.New("messages.vote_saved can't be empty")
+		return
+	}
+
+	if m.IdeaUsage = msgSection.GetString("idea_usage"); m.IdeaUsage == "" {
+		err = errors.New("messages.idea_usage can't be empty")
+		return
+	}
+
+	if m.VoteUsage = msgSection.GetString("vote_usage"); m.VoteUsage == "" {
+		err = errors.New("messages.vote_usage can't be empty")
+		return
+	}
+
+	if m.WishSaved = msgSection.GetString("wish_saved"); m.WishSaved == "" {
+		err = errors.New("messages.wish_saved can't be empty")
+		return
+	}
+
+	if m.WishRemoved = msgSection.GetString("wish_removed"); m.WishRemoved == "" {
+		err = errors.New("messages.wish_removed can't be empty")
+		return
+	}
+
+	if m.WishlistList = msgSection.GetString("wishlist_list"); m.WishlistList == "" {
+		err = errors.New("messages.wishlist_list can't be empty")
+		return
+	}
+
+	if m.WishlistEmpty = msgSection.GetString("wishlist_empty"); m.WishlistEmpty == "" {
+		err = errors.New("messages.wishlist_empty can't be empty")
+		return
+	}
+
+	if m.WishlistShared = msgSection.GetString("wishlist_shared"); m.WishlistShared == "" {
+		err = errors.New("messages.wishlist_shared can't be empty")
+		return
+	}
+
+	if m.WishDMOnly = msgSection.GetString("wish_dm_only"); m.WishDMOnly == "" {
+		err = errors.New("messages.wish_dm_only can't be empty")
+		return
+	}
+
+	if m.WishUsage = msgSection.GetString("wish_usage"); m.WishUsage == "" {
+		err = errors.New("messages.wish_usage can't be empty")
+		return
+	}
+
+	if m.WishNotFound = msgSection.GetString("wish_not_found"); m.WishNotFound == "" {
+		err = errors.New("messages.wish_not_found can't be empty")
+		return
+	}
+
+	if m.ChannelClosing = msgSection.GetString("channel_closing"); m.ChannelClosing == "" && c.ArchiveChannels {
+		err = errors.New("messages.channel_closing can't be empty")
+		return
+	}
+
+	m.BelatedAnnounce = msgSection.GetString("belated_announce") // can be empty, belated notices are not sent then
+
+	if m.CongratsDM = msgSection.GetString("congrats_dm"); m.CongratsDM == "" && c.CongratsDM {
+		err = errors.New("messages.congrats_dm can't be empty")
+		return
+	}
+
+	if m.CongratsChannel = msgSection.GetString("congrats_channel"); m.CongratsChannel == "" && c.CongratsChannel {
+		err = errors.New("messages.congrats_channel can't be empty")
+		return
+	}
+
 	return
 }
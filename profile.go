@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// resolveBDField finds the ID of the custom profile field used for birthdays by its label or ID
+func resolveBDField(sc slack.Client, c *config) error {
+	if c.BDField == "" {
+		logrus.Infoln("Custom profile field for birthdays is not set, using Skype field")
+		return nil
+	}
+
+	id, err := findProfileField(sc, c.BDField)
+	if err != nil {
+		return err
+	}
+	c.BDFieldID = id
+	return nil
+}
+
+// findProfileField finds the ID of the custom profile field by its label or ID
+func findProfileField(sc slack.Client, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	fields, err := sc.GetTeamProfileFields()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get team profile fields")
+	}
+
+	for _, f := range fields {
+		if f.ID == name || strings.EqualFold(f.Label, name) {
+			logrus.Infof("Using custom profile field %q (%s)", f.Label, f.ID)
+			return f.ID, nil
+		}
+	}
+
+	return "", errors.Errorf("custom profile field %q not found", name)
+}
+
+// profileBirthday returns the user's birthday from the custom profile field, falling back to the Skype field
+func profileBirthday(p *slack.UserProfile, c *config) string {
+	if c.BDFieldID != "" {
+		if bd := strings.TrimSpace(p.Fields[c.BDFieldID].Value); bd != "" {
+			return bd
+		}
+	}
+	return p.Skype
+}
+
+// userLocation returns the user's time zone from Slack or the configured location, if it's unknown
+func userLocation(sc slack.Client, c *config, userID string) *time.Location {
+	user, err := sc.GetUserInfo(userID)
+	if err != nil {
+		logrus.WithError(err).Warnf("Unable to get user %s, using default location", userID)
+		return c.Location
+	}
+	if user.TZ == "" {
+		return c.Location
+	}
+
+	loc, err := time.LoadLocation(user.TZ)
+	if err != nil {
+		logrus.WithError(err).Warnf("Unknown time zone %q of user %s, using default location", user.TZ, userID)
+		return c.Location
+	}
+	return loc
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		field   func(s *schedule) uint64
+		want    []int
+		wantErr bool
+	}{
+		{"* * * * *", func(s *schedule) uint64 { return s.hour }, seq(0, 23), false},
+		{"0 10-12 * * *", func(s *schedule) uint64 { return s.hour }, []int{10, 11, 12}, false},
+		{"*/15 * * * *", func(s *schedule) uint64 { return s.minute }, []int{0, 15, 30, 45}, false},
+		{"0 9-18/3 * * *", func(s *schedule) uint64 { return s.hour }, []int{9, 12, 15, 18}, false},
+		{"0 10 1,15,31 * *", func(s *schedule) uint64 { return s.dom }, []int{1, 15, 31}, false},
+		{"0 10 * 1-3,12 *", func(s *schedule) uint64 { return s.month }, []int{1, 2, 3, 12}, false},
+		{"0 10 * * 1-5", func(s *schedule) uint64 { return s.dow }, []int{1, 2, 3, 4, 5}, false},
+		{"0 10 * * 7", func(s *schedule) uint64 { return s.dow }, []int{0, 7}, false},
+		{"0 10 * *", nil, nil, true},
+		{"60 10 * * *", nil, nil, true},
+		{"0 24 * * *", nil, nil, true},
+		{"0 10 0 * *", nil, nil, true},
+		{"0 10 * 13 *", nil, nil, true},
+		{"0 10 * * 8", nil, nil, true},
+		{"0 12-10 * * *", nil, nil, true},
+		{"*/0 * * * *", nil, nil, true},
+		{"a * * * *", nil, nil, true},
+	}
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q): unexpected error %v", tt.expr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var want uint64
+		for _, v := range tt.want {
+			want |= 1 << uint(v)
+		}
+		if got := tt.field(s); got != want {
+			t.Errorf("parseSchedule(%q): expected %b, got %b", tt.expr, want, got)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// same day, strictly after
+		{"0 10-19 * * *", time.Date(2019, 3, 4, 10, 0, 0, 0, time.UTC), time.Date(2019, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 3, 4, 10, 7, 30, 0, time.UTC), time.Date(2019, 3, 4, 10, 15, 0, 0, time.UTC)},
+		// next day
+		{"0 10-19 * * *", time.Date(2019, 3, 4, 19, 0, 0, 0, time.UTC), time.Date(2019, 3, 5, 10, 0, 0, 0, time.UTC)},
+		// across month and year boundaries
+		{"30 9 1 * *", time.Date(2019, 2, 15, 0, 0, 0, 0, time.UTC), time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 10 * * *", time.Date(2019, 12, 31, 12, 0, 0, 0, time.UTC), time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{"0 10 31 * *", time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 5, 31, 10, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// day of week, Sunday as 0 and 7
+		{"0 10 * * 1-5", time.Date(2019, 3, 8, 11, 0, 0, 0, time.UTC), time.Date(2019, 3, 11, 10, 0, 0, 0, time.UTC)},
+		{"0 10 * * 7", time.Date(2019, 3, 4, 11, 0, 0, 0, time.UTC), time.Date(2019, 3, 10, 10, 0, 0, 0, time.UTC)},
+		// either restricted day field matches
+		{"0 10 15 * 1", time.Date(2019, 3, 12, 11, 0, 0, 0, time.UTC), time.Date(2019, 3, 15, 10, 0, 0, 0, time.UTC)},
+		// configured location
+		{"0 10 * * *", time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC).In(moscow), time.Date(2019, 3, 5, 10, 0, 0, 0, moscow)},
+		{"0 10 * * *", time.Date(2019, 3, 4, 6, 0, 0, 0, time.UTC).In(moscow), time.Date(2019, 3, 4, 10, 0, 0, 0, moscow)},
+		// never
+		{"0 10 31 2 *", time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := s.Next(tt.from)
+		if !got.Equal(tt.want) {
+			t.Errorf("%q after %s: expected %s, got %s", tt.expr, tt.from, tt.want, got)
+		}
+		if !got.IsZero() && got.Location() != tt.from.Location() {
+			t.Errorf("%q after %s: expected location %s, got %s", tt.expr, tt.from, tt.from.Location(), got.Location())
+		}
+	}
+}
+
+func seq(from, to int) []int {
+	var result []int
+	for i := from; i <= to; i++ {
+		result = append(result, i)
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandSetBirthday    = "set birthday"
+	commandForgetMe       = "forget me"
+	commandForgetBirthday = "forget birthday"
+
+	privacyPrivate = "private"
+	privacyPublic  = "public"
+)
+
+// handleSetBirthday saves the birthday set by the user.
+// Arguments are: date in any of the supported formats, optional year and optional privacy choice (private or public).
+func handleSetBirthday(rtm slack.RTM, clk clock, db *DB, c *config, msgs *messages, m slack.Message, args string) {
+	bd, err := parseSetBirthdayArgs(args, c.DateOrder, clk.Now().In(c.Location))
+	if err != nil {
+		logrus.WithError(err).Infof("Unable to parse birthday of user %s", m.User)
+		sendReply(rtm, m, bdParseErrorText(msgs, m.User, err))
+		return
+	}
+
+	if err = db.SaveUserBD(m.User, *bd); err != nil {
+		logrus.WithError(err).Errorf("Unable to save birthday of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+
+	// setting the birthday again cancels the opt-out
+	if err = optIn(db, m.User); err != nil {
+		logrus.WithError(err).Errorf("Unable to remove opt-out of user %s", m.User)
+	}
+
+	logrus.Infof("User %s has set the birthday", m.User)
+	sendReply(rtm, m, fmt.Sprintf(msgs.PersonalSaved, m.User))
+}
+
+// handleForgetBirthday removes the birthday set by the user.
+// If all is set, the start date and the wishlist are removed too, and the user is left out of all of the announcements,
+// even if the other sources have the birthday.
+func handleForgetBirthday(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, all bool) {
+	if err := db.DeleteUserBD(m.User); err != nil {
+		logrus.WithError(err).Errorf("Unable to delete birthday of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+
+	if all {
+		if err := db.DeleteUserStartDate(m.User); err != nil {
+			logrus.WithError(err).Errorf("Unable to delete start date of user %s", m.User)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		if err := db.DeleteWishlist(m.User); err != nil {
+			logrus.WithError(err).Errorf("Unable to delete wishlist of user %s", m.User)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		if err := db.SaveUserOptOut(m.User, true); err != nil {
+			logrus.WithError(err).Errorf("Unable to save opt-out of user %s", m.User)
+			sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+			return
+		}
+		commandProfiles.reset()
+	}
+
+	logrus.Infof("User %s has removed the birthday", m.User)
+	sendReply(rtm, m, fmt.Sprintf(msgs.PersonalForgot, m.User))
+}
+
+func parseSetBirthdayArgs(args string, order dateOrder, now time.Time) (*userBD, error) {
+	fields := strings.Fields(args)
+
+	// privacy choice goes last
+	private := false
+	if len(fields) > 0 {
+		switch strings.ToLower(fields[len(fields)-1]) {
+		case privacyPrivate:
+			private = true
+			fields = fields[:len(fields)-1]
+		case privacyPublic:
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	// year can be set separately from the numeric date, like '24.03 1990'
+	year := 0
+	if len(fields) > 1 && yearRe.MatchString(fields[len(fields)-1]) {
+		if _, err := parseBirthday(strings.Join(fields, " "), order, now); err != nil {
+			year, _ = strconv.Atoi(fields[len(fields)-1])
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	d, err := parseBirthday(strings.Join(fields, " "), order, now)
+	if err != nil {
+		return nil, err
+	}
+	if year != 0 {
+		if d.Year != 0 {
+			return nil, errors.Errorf("year is set twice")
+		}
+		d.Year = year
+		if err = validateBDDate(d, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &userBD{Birthday: d.DDMM(), Year: d.Year, Private: private}, nil
+}
+
+// isBDPrivate checks if user has chosen to keep the birthday private
+func isBDPrivate(db *DB, userID string) bool {
+	bd, err := db.GetUserBD(userID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get birthday of user %s", userID)
+		return false
+	}
+	return bd != nil && bd.Private
+}
+
+// isOptedOut checks if user has chosen to be left out of all of the announcements
+func isOptedOut(db *DB, userID string) bool {
+	optOut, err := db.IsUserOptedOut(userID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get opt-out of user %s", userID)
+		return false
+	}
+	return optOut
+}
+
+// optIn cancels the user's opt-out, if there is one
+func optIn(db *DB, userID string) error {
+	if !isOptedOut(db, userID) {
+		return nil
+	}
+	if err := db.SaveUserOptOut(userID, false); err != nil {
+		return err
+	}
+	commandProfiles.reset()
+	logrus.Infof("User %s has opted in", userID)
+	return nil
+}
+
+// commandArgs checks if the text is the command and returns its arguments
+func commandArgs(text, command string) (string, bool) {
+	if strings.ToLower(text) == command {
+		return "", true
+	}
+	if strings.HasPrefix(strings.ToLower(text), command+" ") {
+		return strings.TrimSpace(text[len(command):]), true
+	}
+	return "", false
+}
+
+// isDirectMessage checks if the message was sent in DM with the bot
+func isDirectMessage(m slack.Message) bool {
+	return strings.HasPrefix(m.Conversation, "D")
+}
+
+func sendReply(rtm slack.RTM, m slack.Message, text string) {
+	m.Text = text
+	if err := rtm.SendMessage(m); err != nil {
+		logrus.WithError(err).Errorln("Unable to send message to Slack")
+	}
+}
@@ -15,22 +15,29 @@ const (
 	UserInviteLimit = 30
 )
 
+// Web API methods
 const (
-	postMessageURL          = "https://slack.com/api/chat.postMessage"
-	conversationsCreateURL  = "https://slack.com/api/conversations.create"
-	conversationsInfoURL    = "https://slack.com/api/conversations.info"
-	conversationsInviteURL  = "https://slack.com/api/conversations.invite"
-	conversationsListURL    = "https://slack.com/api/conversations.list"
-	conversationsMembersURL = "https://slack.com/api/conversations.members"
-	imListURL               = "https://slack.com/api/im.list"
-	userProfileURL          = "https://slack.com/api/users.profile.get"
+	postMessageMethod          = "chat.postMessage"
+	conversationsArchiveMethod = "conversations.archive"
+	conversationsCreateMethod  = "conversations.create"
+	conversationsInfoMethod    = "conversations.info"
+	conversationsInviteMethod  = "conversations.invite"
+	conversationsListMethod    = "conversations.list"
+	conversationsMembersMethod = "conversations.members"
+	imListMethod               = "im.list"
+	userInfoMethod             = "users.info"
+	userProfileMethod          = "users.profile.get"
+	teamProfileMethod          = "team.profile.get"
+	authTestMethod             = "auth.test"
+)
 
+const (
 	alreadyInChannelErrorMsg = "already_in_channel"
 	cantInviteSelfErrorMsg   = "cant_invite_self"
 )
 
 // SendAPIMessage sends a message with Web API
-func SendAPIMessage(token, chanID, message string) error {
+func (c *HTTPClient) SendAPIMessage(chanID, message string) error {
 	var response struct {
 		OK      bool   `json:"ok"`
 		Error   string `json:"error,omitempty"`
@@ -60,9 +67,9 @@ func SendAPIMessage(token, chanID, message string) error {
 	if err != nil {
 		return errors.Wrap(err, "unable to marshal request")
 	}
-	headers := map[string]string{"Authorization": "Bearer " + token}
+	headers := map[string]string{"Authorization": "Bearer " + c.legacyToken}
 
-	body, err = makeRequest(postMessageURL, methodPOST, contentJSON, body, nil, headers)
+	body, err = makeRequest(c.baseURL+postMessageMethod, methodPOST, contentJSON, body, nil, headers)
 	if err != nil {
 		return errors.Wrap(err, "unable to make POST request")
 	}
@@ -79,7 +86,7 @@ func SendAPIMessage(token, chanID, message string) error {
 }
 
 // CreateNewConversation creates new Slack conversation and returns its ID and error, if any
-func CreateNewConversation(token, conversationName string, isPrivate bool) (string, error) {
+func (c *HTTPClient) CreateNewConversation(conversationName string, isPrivate bool) (string, error) {
 	var response struct {
 		OK           bool         `json:"ok"`
 		Error        string       `json:"error"`
@@ -87,11 +94,11 @@ func CreateNewConversation(token, conversationName string, isPrivate bool) (stri
 	}
 
 	params := map[string]string{
-		"token":      token,
+		"token":      c.legacyToken,
 		"name":       conversationName,
 		"is_private": strconv.FormatBool(isPrivate),
 	}
-	respBody, err := makeRequest(conversationsCreateURL, methodGET, contentEncoded, nil, params, nil)
+	respBody, err := makeRequest(c.baseURL+conversationsCreateMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to make GET request")
 	}
@@ -108,15 +115,15 @@ func CreateNewConversation(token, conversationName string, isPrivate bool) (stri
 }
 
 // GetConversationInfo returns info about the Slack conversation by its ID
-func GetConversationInfo(token, chanID string) (*Conversation, error) {
+func (c *HTTPClient) GetConversationInfo(chanID string) (*Conversation, error) {
 	var response struct {
 		OK           bool         `json:"ok"`
 		Error        string       `json:"error"`
 		Conversation Conversation `json:"channel"`
 	}
 
-	params := map[string]string{"token": token, "channel": chanID}
-	respBody, err := makeRequest(conversationsInfoURL, methodGET, contentEncoded, nil, params, nil)
+	params := map[string]string{"token": c.legacyToken, "channel": chanID}
+	respBody, err := makeRequest(c.baseURL+conversationsInfoMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to make GET request")
 	}
@@ -133,7 +140,7 @@ func GetConversationInfo(token, chanID string) (*Conversation, error) {
 }
 
 // GetConversations returns info about all of the public and private Slack conversations in the workspace
-func GetConversations(token string, withArchived bool) ([]Conversation, error) {
+func (c *HTTPClient) GetConversations(withArchived bool) ([]Conversation, error) {
 	var response struct {
 		OK            bool           `json:"ok"`
 		Error         string         `json:"error"`
@@ -141,12 +148,12 @@ func GetConversations(token string, withArchived bool) ([]Conversation, error) {
 	}
 
 	params := map[string]string{
-		"token":            token,
+		"token":            c.legacyToken,
 		"limit":            "10000",
 		"types":            "public_channel,private_channel",
 		"exclude_archived": fmt.Sprintf("%t", !withArchived),
 	}
-	respBody, err := makeRequest(conversationsListURL, methodGET, contentEncoded, nil, params, nil)
+	respBody, err := makeRequest(c.baseURL+conversationsListMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to make GET request")
 	}
@@ -162,16 +169,40 @@ func GetConversations(token string, withArchived bool) ([]Conversation, error) {
 	return response.Conversations, nil
 }
 
+// ArchiveConversation archives the Slack conversation by its ID
+func (c *HTTPClient) ArchiveConversation(chanID string) error {
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	params := map[string]string{"token": c.legacyToken, "channel": chanID}
+	respBody, err := makeRequest(c.baseURL+conversationsArchiveMethod, methodGET, contentEncoded, nil, params, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to make GET request")
+	}
+
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	if !response.OK {
+		return errors.Errorf("API error: %s", response.Error)
+	}
+
+	return nil
+}
+
 // GetConversationMembers returns the Slack conversation member list by conversation ID
-func GetConversationMembers(token, chanID string) ([]string, error) {
+func (c *HTTPClient) GetConversationMembers(chanID string) ([]string, error) {
 	var response struct {
 		OK      bool     `json:"ok"`
 		Error   string   `json:"error"`
 		Members []string `json:"members"`
 	}
 
-	params := map[string]string{"token": token, "channel": chanID}
-	respBody, err := makeRequest(conversationsMembersURL, methodGET, contentEncoded, nil, params, nil)
+	params := map[string]string{"token": c.legacyToken, "channel": chanID}
+	respBody, err := makeRequest(c.baseURL+conversationsMembersMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to make GET request")
 	}
@@ -188,15 +219,15 @@ func GetConversationMembers(token, chanID string) ([]string, error) {
 }
 
 // GetUserProfile returns the Slack user's profile by user ID
-func GetUserProfile(token, userID string) (*UserProfile, error) {
+func (c *HTTPClient) GetUserProfile(userID string) (*UserProfile, error) {
 	var response struct {
 		OK      bool        `json:"ok"`
 		Error   string      `json:"error"`
 		Profile UserProfile `json:"profile"`
 	}
 
-	params := map[string]string{"token": token, "user": userID}
-	respBody, err := makeRequest(userProfileURL, methodGET, contentEncoded, nil, params, nil)
+	params := map[string]string{"token": c.legacyToken, "user": userID}
+	respBody, err := makeRequest(c.baseURL+userProfileMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to make GET request")
 	}
@@ -213,16 +244,93 @@ func GetUserProfile(token, userID string) (*UserProfile, error) {
 	return &response.Profile, nil
 }
 
+// GetUserInfo returns the Slack user by user ID
+func (c *HTTPClient) GetUserInfo(userID string) (*User, error) {
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  User   `json:"user"`
+	}
+
+	params := map[string]string{"token": c.legacyToken, "user": userID}
+	respBody, err := makeRequest(c.baseURL+userInfoMethod, methodGET, contentEncoded, nil, params, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to make GET request")
+	}
+
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	if !response.OK {
+		return nil, errors.Errorf("API error: %s", response.Error)
+	}
+
+	return &response.User, nil
+}
+
+// GetTeamProfileFields returns the custom profile fields of the workspace
+func (c *HTTPClient) GetTeamProfileFields() ([]TeamProfileField, error) {
+	var response struct {
+		OK      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Profile struct {
+			Fields []TeamProfileField `json:"fields"`
+		} `json:"profile"`
+	}
+
+	params := map[string]string{"token": c.legacyToken}
+	respBody, err := makeRequest(c.baseURL+teamProfileMethod, methodGET, contentEncoded, nil, params, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to make GET request")
+	}
+
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	if !response.OK {
+		return nil, errors.Errorf("API error: %s", response.Error)
+	}
+
+	return response.Profile.Fields, nil
+}
+
+// AuthTest returns the ID of the user who owns the Web API token
+func (c *HTTPClient) AuthTest() (string, error) {
+	var response struct {
+		OK     bool   `json:"ok"`
+		Error  string `json:"error"`
+		UserID string `json:"user_id"`
+	}
+
+	params := map[string]string{"token": c.legacyToken}
+	respBody, err := makeRequest(c.baseURL+authTestMethod, methodGET, contentEncoded, nil, params, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to make GET request")
+	}
+
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return "", errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	if !response.OK {
+		return "", errors.Errorf("API error: %s", response.Error)
+	}
+
+	return response.UserID, nil
+}
+
 // FindDMByUserID returns Slack IM ID for the provided user ID
-func FindDMByUserID(token, userID string) (string, error) {
+func (c *HTTPClient) FindDMByUserID(userID string) (string, error) {
 	var response struct {
 		OK    bool   `json:"ok"`
 		Error string `json:"error"`
 		IMs   []IM   `json:"ims"`
 	}
 
-	params := map[string]string{"token": token}
-	respBody, err := makeRequest(imListURL, methodGET, contentEncoded, nil, params, nil)
+	params := map[string]string{"token": c.legacyToken}
+	respBody, err := makeRequest(c.baseURL+imListMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to make GET request")
 	}
@@ -245,7 +353,7 @@ func FindDMByUserID(token, userID string) (string, error) {
 }
 
 // InviteMembersToConversation adds members from the slice to the Slack conversation
-func InviteMembersToConversation(token, chanID string, memberIDs []string) error {
+func (c *HTTPClient) InviteMembersToConversation(chanID string, memberIDs []string) error {
 	var response struct {
 		OK           bool         `json:"ok"`
 		Error        string       `json:"error,omitempty"`
@@ -271,9 +379,9 @@ func InviteMembersToConversation(token, chanID string, memberIDs []string) error
 		if err != nil {
 			return errors.Wrapf(err, "unable to marshal request for user %s", id)
 		}
-		headers := map[string]string{"Authorization": "Bearer " + token}
+		headers := map[string]string{"Authorization": "Bearer " + c.legacyToken}
 
-		body, err = makeRequest(conversationsInviteURL, methodPOST, contentJSON, body, nil, headers)
+		body, err = makeRequest(c.baseURL+conversationsInviteMethod, methodPOST, contentJSON, body, nil, headers)
 		if err != nil {
 			return errors.Wrapf(err, "unable to make POST request for user %s", id)
 		}
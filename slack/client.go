@@ -0,0 +1,63 @@
+package slack
+
+import (
+	"strings"
+
+	ws "golang.org/x/net/websocket"
+)
+
+// Client describes the subset of Slack API used by the bot
+type Client interface {
+	// messaging
+	SendAPIMessage(chanID, message string) error
+
+	// conversations
+	CreateNewConversation(conversationName string, isPrivate bool) (string, error)
+	GetConversationInfo(chanID string) (*Conversation, error)
+	GetConversations(withArchived bool) ([]Conversation, error)
+	GetConversationMembers(chanID string) ([]string, error)
+	InviteMembersToConversation(chanID string, memberIDs []string) error
+	ArchiveConversation(chanID string) error
+	FindDMByUserID(userID string) (string, error)
+
+	// profiles
+	GetUserProfile(userID string) (*UserProfile, error)
+	GetUserInfo(userID string) (*User, error)
+	GetTeamProfileFields() ([]TeamProfileField, error)
+	AuthTest() (userID string, err error)
+
+	// Real Time Messaging API
+	InitRTM() (userID string, err error)
+	DialRTM() (RTM, error)
+}
+
+// RTM describes a Real Time Messaging API connection
+type RTM interface {
+	GetMessage() (Message, error)
+	SendMessage(m Message) error
+	Close() error
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+// HTTPClient is the Client implementation which talks to Slack over HTTP
+type HTTPClient struct {
+	baseURL     string
+	botToken    string
+	legacyToken string
+
+	wsConfig *ws.Config
+}
+
+// NewHTTPClient creates new HTTPClient.
+// Bot token is used for the Real Time Messaging API, legacy token - for the Web API.
+// Base URL can be empty, DefaultBaseURL is used in that case.
+func NewHTTPClient(baseURL, botToken, legacyToken string) *HTTPClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &HTTPClient{baseURL: baseURL, botToken: botToken, legacyToken: legacyToken}
+}
@@ -17,8 +17,11 @@ const (
 	contentEncoded = "application/x-www-form-urlencoded; charset=utf-8"
 	contentJSON    = "application/json; charset=utf-8"
 
-	apiURL   = "https://api.slack.com/"
-	startURL = "https://slack.com/api/rtm.start"
+	apiURL         = "https://api.slack.com/"
+	rtmStartMethod = "rtm.start"
+
+	// DefaultBaseURL is the base URL of Slack Web API
+	DefaultBaseURL = "https://slack.com/api/"
 )
 
 var (
@@ -29,24 +32,34 @@ var (
 
 var msgCounter uint64
 
-// GetWSMessage receives a message from RTM API
-func GetWSMessage(conn *ws.Conn) (m Message, err error) {
-	if err = conn.SetReadDeadline(time.Now().Add(wsDeadline)); err != nil {
+// wsRTM is the RTM implementation based on websocket connection
+type wsRTM struct {
+	conn *ws.Conn
+}
+
+// GetMessage receives a message from RTM API
+func (r *wsRTM) GetMessage() (m Message, err error) {
+	if err = r.conn.SetReadDeadline(time.Now().Add(wsDeadline)); err != nil {
 		return
 	}
-	err = ws.JSON.Receive(conn, &m)
+	err = ws.JSON.Receive(r.conn, &m)
 	return
 }
 
-// SendWSMessage sends a message with RTM API
-func SendWSMessage(conn *ws.Conn, m Message) error {
+// SendMessage sends a message with RTM API
+func (r *wsRTM) SendMessage(m Message) error {
 	m.ID = atomic.AddUint64(&msgCounter, 1)
-	return ws.JSON.Send(conn, m)
+	return ws.JSON.Send(r.conn, m)
+}
+
+// Close closes the websocket connection
+func (r *wsRTM) Close() error {
+	return r.conn.Close()
 }
 
-// InitWSConfig creates a websocket-based Real Time API session
-// and returns the websocket config and the ID of the bot/user whom the token belongs to.
-func InitWSConfig(token string) (config *ws.Config, userID string, err error) {
+// InitRTM creates a websocket-based Real Time API session
+// and returns the ID of the bot/user whom the bot token belongs to.
+func (c *HTTPClient) InitRTM() (userID string, err error) {
 	var response struct {
 		OK    bool   `json:"ok"`
 		Error string `json:"error"`
@@ -56,8 +69,8 @@ func InitWSConfig(token string) (config *ws.Config, userID string, err error) {
 		} `json:"self"`
 	}
 
-	params := map[string]string{"token": token}
-	respBody, err := makeRequest(startURL, methodGET, contentEncoded, nil, params, nil)
+	params := map[string]string{"token": c.botToken}
+	respBody, err := makeRequest(c.baseURL+rtmStartMethod, methodGET, contentEncoded, nil, params, nil)
 	if err != nil {
 		err = errors.Wrap(err, "unable to make GET request")
 		return
@@ -69,11 +82,11 @@ func InitWSConfig(token string) (config *ws.Config, userID string, err error) {
 	}
 
 	if !response.OK {
-		err = errors.Wrap(err, "request was unsuccessful")
+		err = errors.Errorf("API error: %s", response.Error)
 		return
 	}
 
-	if config, err = ws.NewConfig(response.URL, apiURL); err != nil {
+	if c.wsConfig, err = ws.NewConfig(response.URL, apiURL); err != nil {
 		err = errors.Wrap(err, "unable to create websocket config")
 		return
 	}
@@ -82,12 +95,17 @@ func InitWSConfig(token string) (config *ws.Config, userID string, err error) {
 	return
 }
 
-// DialWS wraps ws.DialConfig
-func DialWS(config *ws.Config) (conn *ws.Conn, err error) {
-	if conn, err = ws.DialConfig(config); err != nil {
-		err = errors.Wrap(err, "unable to dial Slack's websocket")
+// DialRTM dials the websocket of the RTM session created by InitRTM
+func (c *HTTPClient) DialRTM() (RTM, error) {
+	if c.wsConfig == nil {
+		return nil, errors.New("RTM session is not initialized")
 	}
-	return
+
+	conn, err := ws.DialConfig(c.wsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to dial Slack's websocket")
+	}
+	return &wsRTM{conn}, nil
 }
 
 func makeRequest(url, method, contentType string, body []byte, params, headers map[string]string) ([]byte, error) {
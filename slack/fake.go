@@ -0,0 +1,407 @@
+package slack
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Call describes a single FakeClient method call
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+var _ Client = (*FakeClient)(nil)
+
+// FakeClient is the in-memory Client implementation which records all of the calls made.
+// It's meant to be used for offline testing of the bot logic.
+type FakeClient struct {
+	BotUID   string
+	Profiles map[string]*UserProfile
+	Channels map[string]*Conversation
+	Members  map[string][]string
+	IMs      map[string]string // user ID -> IM ID
+	TZs      map[string]string // user ID -> time zone
+
+	TeamProfileFields []TeamProfileField
+
+	Calls    []Call
+	Messages []Message // messages sent via Web API
+
+	RTMConn *FakeRTM
+
+	mu        sync.Mutex
+	idCounter int
+	dials     int
+}
+
+// NewFakeClient creates new empty FakeClient
+func NewFakeClient(botUID string) *FakeClient {
+	return &FakeClient{
+		BotUID:   botUID,
+		Profiles: make(map[string]*UserProfile),
+		Channels: make(map[string]*Conversation),
+		Members:  make(map[string][]string),
+		IMs:      make(map[string]string),
+		TZs:      make(map[string]string),
+		RTMConn:  NewFakeRTM(),
+	}
+}
+
+// AddUser seeds the user profile and its IM
+func (f *FakeClient) AddUser(p UserProfile) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Profiles[p.ID] = &p
+	f.IMs[p.ID] = "D" + p.ID
+}
+
+// SetUserTZ sets the user's time zone
+func (f *FakeClient) SetUserTZ(userID, tz string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.TZs[userID] = tz
+}
+
+// AddChannel seeds the conversation with its members
+func (f *FakeClient) AddChannel(ch Conversation, members ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Channels[ch.ID] = &ch
+	f.Members[ch.ID] = append([]string(nil), members...)
+}
+
+// CallsTo returns all of the recorded calls of the method
+func (f *FakeClient) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []Call
+	for _, c := range f.Calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// SentMessages returns all of the messages sent via Web API to the conversation
+func (f *FakeClient) SentMessages(chanID string) []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var msgs []Message
+	for _, m := range f.Messages {
+		if m.Conversation == chanID {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+func (f *FakeClient) record(method string, args ...interface{}) {
+	f.Calls = append(f.Calls, Call{method, args})
+}
+
+// SendAPIMessage records the message
+func (f *FakeClient) SendAPIMessage(chanID, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SendAPIMessage", chanID, message)
+
+	ch, ok := f.Channels[chanID]
+	if !ok && !f.isIM(chanID) {
+		return errors.New("API error: channel_not_found")
+	}
+	if ok && ch.IsArchived {
+		return errors.New("API error: is_archived")
+	}
+
+	f.Messages = append(f.Messages, Message{Type: TypeMessage, Conversation: chanID, Text: message})
+	return nil
+}
+
+// CreateNewConversation creates new in-memory conversation
+func (f *FakeClient) CreateNewConversation(conversationName string, isPrivate bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CreateNewConversation", conversationName, isPrivate)
+
+	for _, ch := range f.Channels {
+		if ch.Name == conversationName {
+			return "", errors.New("API error: name_taken")
+		}
+	}
+
+	f.idCounter++
+	ch := &Conversation{
+		ID:        "C" + strconv.Itoa(f.idCounter),
+		Name:      conversationName,
+		NameNorm:  conversationName,
+		Created:   time.Now().Unix(),
+		Creator:   f.BotUID,
+		IsMember:  true,
+		IsPrivate: isPrivate,
+	}
+	f.Channels[ch.ID] = ch
+	f.Members[ch.ID] = []string{f.BotUID}
+	return ch.ID, nil
+}
+
+// GetConversationInfo returns the in-memory conversation
+func (f *FakeClient) GetConversationInfo(chanID string) (*Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetConversationInfo", chanID)
+
+	ch, ok := f.Channels[chanID]
+	if !ok {
+		return nil, errors.New("API error: channel_not_found")
+	}
+	result := *ch
+	return &result, nil
+}
+
+// GetConversations returns all of the in-memory conversations
+func (f *FakeClient) GetConversations(withArchived bool) ([]Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetConversations", withArchived)
+
+	var result []Conversation
+	for _, ch := range f.Channels {
+		if ch.IsArchived && !withArchived {
+			continue
+		}
+		result = append(result, *ch)
+	}
+	return result, nil
+}
+
+// GetConversationMembers returns the in-memory conversation members
+func (f *FakeClient) GetConversationMembers(chanID string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetConversationMembers", chanID)
+
+	members, ok := f.Members[chanID]
+	if !ok {
+		return nil, errors.New("API error: channel_not_found")
+	}
+	return append([]string(nil), members...), nil
+}
+
+// InviteMembersToConversation adds members to the in-memory conversation
+func (f *FakeClient) InviteMembersToConversation(chanID string, memberIDs []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("InviteMembersToConversation", chanID, append([]string(nil), memberIDs...))
+
+	members, ok := f.Members[chanID]
+	if !ok {
+		return errors.New("API error: channel_not_found")
+	}
+
+	for _, id := range memberIDs {
+		found := false
+		for _, m := range members {
+			if m == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			members = append(members, id)
+		}
+	}
+	f.Members[chanID] = members
+	return nil
+}
+
+// ArchiveConversation marks the in-memory conversation as archived
+func (f *FakeClient) ArchiveConversation(chanID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ArchiveConversation", chanID)
+
+	ch, ok := f.Channels[chanID]
+	if !ok {
+		return errors.New("API error: channel_not_found")
+	}
+	if ch.IsArchived {
+		return errors.New("API error: already_archived")
+	}
+	ch.IsArchived = true
+	return nil
+}
+
+// FindDMByUserID returns the in-memory IM ID of the user
+func (f *FakeClient) FindDMByUserID(userID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("FindDMByUserID", userID)
+
+	if im, ok := f.IMs[userID]; ok {
+		return im, nil
+	}
+	return "", errors.New("user not found")
+}
+
+// GetUserProfile returns the in-memory user profile
+func (f *FakeClient) GetUserProfile(userID string) (*UserProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetUserProfile", userID)
+
+	p, ok := f.Profiles[userID]
+	if !ok {
+		return nil, errors.New("API error: user_not_found")
+	}
+	result := *p
+	return &result, nil
+}
+
+// GetUserInfo returns the in-memory user, based on its profile
+func (f *FakeClient) GetUserInfo(userID string) (*User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetUserInfo", userID)
+
+	p, ok := f.Profiles[userID]
+	if !ok {
+		return nil, errors.New("API error: user_not_found")
+	}
+	return &User{ID: userID, Name: p.DisplayName, RealName: p.RealName, TZ: f.TZs[userID]}, nil
+}
+
+// GetTeamProfileFields returns the in-memory custom profile fields
+func (f *FakeClient) GetTeamProfileFields() ([]TeamProfileField, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetTeamProfileFields")
+
+	return append([]TeamProfileField(nil), f.TeamProfileFields...), nil
+}
+
+// AuthTest returns the bot user ID, as the fake conversations are created by the bot
+func (f *FakeClient) AuthTest() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("AuthTest")
+
+	return f.BotUID, nil
+}
+
+// InitRTM returns the bot user ID
+func (f *FakeClient) InitRTM() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("InitRTM")
+
+	return f.BotUID, nil
+}
+
+// DialRTM returns the in-memory RTM connection.
+// First dial returns RTMConn, so that the messages can be queued before the bot connects,
+// every next one replaces it with a fresh connection, the same way the real reconnect does.
+func (f *FakeClient) DialRTM() (RTM, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("DialRTM")
+
+	if f.dials > 0 {
+		f.RTMConn = NewFakeRTM()
+	}
+	f.dials++
+	return f.RTMConn, nil
+}
+
+func (f *FakeClient) isIM(chanID string) bool {
+	for _, im := range f.IMs {
+		if im == chanID {
+			return true
+		}
+	}
+	return false
+}
+
+// FakeRTM is the in-memory RTM connection.
+// Messages pushed with Push are received by GetMessage, messages sent with SendMessage are recorded.
+type FakeRTM struct {
+	Sent []Message
+
+	incoming chan Message
+	closed   chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+}
+
+// NewFakeRTM creates new FakeRTM
+func NewFakeRTM() *FakeRTM {
+	return &FakeRTM{
+		incoming: make(chan Message, 100),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Push queues the message to be received by GetMessage
+func (r *FakeRTM) Push(m Message) {
+	r.incoming <- m
+}
+
+// SentMessages returns a copy of the messages sent via RTM
+func (r *FakeRTM) SentMessages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Message(nil), r.Sent...)
+}
+
+// GetMessage returns the next pushed message or a timeout error, if there is none
+func (r *FakeRTM) GetMessage() (Message, error) {
+	select {
+	case <-r.closed:
+		return Message{}, io.EOF
+	case m := <-r.incoming:
+		return m, nil
+	case <-time.After(wsDeadline):
+		return Message{}, timeoutError{}
+	}
+}
+
+// SendMessage records the message
+func (r *FakeRTM) SendMessage(m Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	m.ID = atomic.AddUint64(&msgCounter, 1)
+	r.Sent = append(r.Sent, m)
+	return nil
+}
+
+// Close closes the connection
+func (r *FakeRTM) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+// timeoutError implements net.Error the same way websocket read deadline does
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
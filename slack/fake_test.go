@@ -0,0 +1,95 @@
+package slack
+
+import (
+	"io"
+	"testing"
+)
+
+func TestFakeClientDialRTM(t *testing.T) {
+	f := NewFakeClient("UBOT")
+	f.RTMConn.Push(Message{Type: TypeMessage, Text: "queued"})
+
+	rtm, err := f.DialRTM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := rtm.GetMessage()
+	if err != nil || m.Text != "queued" {
+		t.Fatalf("expected queued message, got %+v, %v", m, err)
+	}
+
+	// reconnect after the connection is closed
+	rtm.Close()
+	if _, err = rtm.GetMessage(); err != io.EOF {
+		t.Fatalf("expected EOF from closed connection, got %v", err)
+	}
+
+	next, err := f.DialRTM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next == rtm {
+		t.Fatal("expected fresh connection on redial")
+	}
+	if err = next.SendMessage(Message{Text: "hello"}); err != nil {
+		t.Fatalf("unable to send message to fresh connection: %v", err)
+	}
+	if sent := f.RTMConn.SentMessages(); len(sent) != 1 || sent[0].Text != "hello" {
+		t.Fatalf("expected message in the current connection, got %+v", sent)
+	}
+}
+
+func TestFakeClientConversations(t *testing.T) {
+	f := NewFakeClient("UBOT")
+	f.AddUser(UserProfile{ID: "U1"})
+	f.AddUser(UserProfile{ID: "U2"})
+
+	chanID, err := f.CreateNewConversation("lee-bd-2019", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = f.CreateNewConversation("lee-bd-2019", true); err == nil || err.Error() != "API error: name_taken" {
+		t.Fatalf("expected name_taken error, got %v", err)
+	}
+
+	if err = f.InviteMembersToConversation(chanID, []string{"U1", "U2", "U1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	members, err := f.GetConversationMembers(chanID)
+	if err != nil || len(members) != 3 {
+		t.Fatalf("expected bot and 2 members, got %v, %v", members, err)
+	}
+
+	if err = f.SendAPIMessage(chanID, "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = f.SendAPIMessage("CUNKNOWN", "hi"); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+	im, err := f.FindDMByUserID("U1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = f.SendAPIMessage(im, "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msgs := f.SentMessages(chanID); len(msgs) != 1 || msgs[0].Text != "hi" {
+		t.Fatalf("expected single message in channel, got %+v", msgs)
+	}
+
+	if err = f.ArchiveConversation(chanID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = f.ArchiveConversation(chanID); err == nil || err.Error() != "API error: already_archived" {
+		t.Fatalf("expected already_archived error, got %v", err)
+	}
+	if active, _ := f.GetConversations(false); len(active) != 0 {
+		t.Fatalf("expected no active conversations, got %+v", active)
+	}
+	if all, _ := f.GetConversations(true); len(all) != 1 {
+		t.Fatalf("expected archived conversation, got %+v", all)
+	}
+	if calls := f.CallsTo("ArchiveConversation"); len(calls) != 2 {
+		t.Fatalf("expected 2 recorded calls, got %d", len(calls))
+	}
+}
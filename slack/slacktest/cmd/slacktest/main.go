@@ -0,0 +1,39 @@
+/*
+slacktest - standalone simulated Slack workspace for running the bot binary in CI
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nezorflame/bd-reminder-bot/slack/slacktest"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	// get flags
+	addrPtr := flag.String("addr", "127.0.0.1:8765", "address to listen on")
+	workspacePtr := flag.String("workspace", "", "JSON file with the workspace seed data")
+	flag.Parse()
+
+	w := slacktest.Workspace{BotUID: "UBOT"}
+	if *workspacePtr != "" {
+		data, err := ioutil.ReadFile(*workspacePtr)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Unable to read workspace file")
+		}
+		if err = json.Unmarshal(data, &w); err != nil {
+			logrus.WithError(err).Fatalf("Unable to parse workspace file")
+		}
+	}
+
+	s := slacktest.NewServerFromWorkspace(w)
+	logrus.Infof("Serving simulated Slack workspace at http://%s/api/", *addrPtr)
+	if err := http.ListenAndServe(*addrPtr, s.Handler()); err != nil {
+		logrus.WithError(err).Fatalf("Server failed")
+	}
+}
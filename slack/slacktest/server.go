@@ -0,0 +1,547 @@
+/*
+Package slacktest provides a local stand-in for the subset of Slack Web and RTM API used by the bot.
+It's meant to be used for integration tests against a simulated workspace.
+*/
+package slacktest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/sirupsen/logrus"
+	ws "golang.org/x/net/websocket"
+)
+
+// Workspace describes the seed data of the simulated workspace
+type Workspace struct {
+	BotUID   string               `json:"bot_uid"`
+	Profiles []slack.UserProfile  `json:"profiles"`
+	Channels []slack.Conversation `json:"channels"`
+	Members  map[string][]string  `json:"members"` // channel ID -> user IDs
+	TZs      map[string]string    `json:"tz"`      // user ID -> time zone
+
+	ProfileFields []slack.TeamProfileField `json:"profile_fields"`
+}
+
+// Server is the simulated Slack workspace, served over HTTP
+type Server struct {
+	botUID   string
+	profiles map[string]slack.UserProfile
+	channels map[string]*slack.Conversation
+	members  map[string][]string
+	ims      map[string]string // user ID -> IM ID
+	tzs      map[string]string // user ID -> time zone
+	fields   []slack.TeamProfileField
+
+	messages    []slack.Message // messages posted via Web API
+	rtmMessages []slack.Message // messages sent by the bot via RTM
+	wsConns     map[*ws.Conn]struct{}
+
+	idCounter int
+	mu        sync.Mutex
+
+	srv *httptest.Server
+	mux *http.ServeMux
+}
+
+// NewServer creates new Server with the provided bot user ID.
+// Server is not started until Start is called, use Handler to serve it on a custom listener.
+func NewServer(botUID string) *Server {
+	s := &Server{
+		botUID:   botUID,
+		profiles: make(map[string]slack.UserProfile),
+		channels: make(map[string]*slack.Conversation),
+		members:  make(map[string][]string),
+		ims:      make(map[string]string),
+		tzs:      make(map[string]string),
+		wsConns:  make(map[*ws.Conn]struct{}),
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/api/chat.postMessage", s.handlePostMessage)
+	s.mux.HandleFunc("/api/conversations.archive", s.handleConversationsArchive)
+	s.mux.HandleFunc("/api/conversations.create", s.handleConversationsCreate)
+	s.mux.HandleFunc("/api/conversations.info", s.handleConversationsInfo)
+	s.mux.HandleFunc("/api/conversations.list", s.handleConversationsList)
+	s.mux.HandleFunc("/api/conversations.members", s.handleConversationsMembers)
+	s.mux.HandleFunc("/api/conversations.invite", s.handleConversationsInvite)
+	s.mux.HandleFunc("/api/im.list", s.handleIMList)
+	s.mux.HandleFunc("/api/users.info", s.handleUserInfo)
+	s.mux.HandleFunc("/api/users.profile.get", s.handleUserProfile)
+	s.mux.HandleFunc("/api/team.profile.get", s.handleTeamProfile)
+	s.mux.HandleFunc("/api/auth.test", s.handleAuthTest)
+	s.mux.HandleFunc("/api/rtm.start", s.handleRTMStart)
+	s.mux.Handle("/ws", ws.Handler(s.handleWS))
+	return s
+}
+
+// NewServerFromWorkspace creates new Server seeded with the workspace data
+func NewServerFromWorkspace(w Workspace) *Server {
+	s := NewServer(w.BotUID)
+	for _, p := range w.Profiles {
+		s.AddUser(p)
+	}
+	for _, ch := range w.Channels {
+		s.AddChannel(ch, w.Members[ch.ID]...)
+	}
+	for id, tz := range w.TZs {
+		s.SetUserTZ(id, tz)
+	}
+	for _, f := range w.ProfileFields {
+		s.AddProfileField(f)
+	}
+	return s
+}
+
+// Start starts the server on a random local port
+func (s *Server) Start() {
+	s.srv = httptest.NewServer(s.mux)
+}
+
+// Handler returns the server's HTTP handler, for serving it on a custom listener
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.mu.Lock()
+	for conn := range s.wsConns {
+		conn.Close() // not interested in this error, so skipping
+	}
+	s.mu.Unlock()
+
+	if s.srv != nil {
+		s.srv.Close()
+	}
+}
+
+// URL returns the server's Web API base URL, to be used with slack.NewHTTPClient
+func (s *Server) URL() string {
+	return s.srv.URL + "/api/"
+}
+
+// AddUser seeds the user profile and its IM
+func (s *Server) AddUser(p slack.UserProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profiles[p.ID] = p
+	s.ims[p.ID] = "D" + p.ID
+}
+
+// SetUserTZ sets the user's time zone
+func (s *Server) SetUserTZ(userID, tz string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tzs[userID] = tz
+}
+
+// AddChannel seeds the conversation with its members
+func (s *Server) AddChannel(ch slack.Conversation, members ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels[ch.ID] = &ch
+	s.members[ch.ID] = append([]string(nil), members...)
+}
+
+// AddProfileField seeds the custom profile field of the workspace
+func (s *Server) AddProfileField(f slack.TeamProfileField) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fields = append(s.fields, f)
+}
+
+// Messages returns all of the messages posted via Web API
+func (s *Server) Messages() []slack.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]slack.Message(nil), s.messages...)
+}
+
+// RTMMessages returns all of the messages sent by the bot via RTM
+func (s *Server) RTMMessages() []slack.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]slack.Message(nil), s.rtmMessages...)
+}
+
+// Channels returns all of the conversations in the workspace
+func (s *Server) Channels() []slack.Conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]slack.Conversation, 0, len(s.channels))
+	for _, ch := range s.channels {
+		result = append(result, *ch)
+	}
+	return result
+}
+
+// Members returns the member list of the conversation
+func (s *Server) Members(chanID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.members[chanID]...)
+}
+
+// SendRTMMessage sends the message to all of the connected RTM clients, as if it was written by a user
+func (s *Server) SendRTMMessage(m slack.Message) {
+	if m.Type == "" {
+		m.Type = slack.TypeMessage
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.wsConns {
+		if err := ws.JSON.Send(conn, m); err != nil {
+			logrus.WithError(err).Warnln("Unable to send RTM message")
+		}
+	}
+}
+
+func (s *Server) handlePostMessage(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Conversation string `json:"channel"`
+		Text         string `json:"text"`
+	}
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "invalid_json")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.channels[request.Conversation]
+	if !ok && !s.isIM(request.Conversation) {
+		writeError(w, "channel_not_found")
+		return
+	}
+	if ok && ch.IsArchived {
+		writeError(w, "is_archived")
+		return
+	}
+
+	s.messages = append(s.messages, slack.Message{
+		Type:         slack.TypeMessage,
+		User:         s.botUID,
+		Conversation: request.Conversation,
+		Text:         request.Text,
+	})
+	writeOK(w, map[string]interface{}{"ts": strconv.FormatInt(time.Now().UnixNano(), 10)})
+}
+
+func (s *Server) handleConversationsCreate(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+	name := r.FormValue("name")
+	if name == "" {
+		writeError(w, "invalid_name_required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ch := range s.channels {
+		if ch.Name == name {
+			writeError(w, "name_taken")
+			return
+		}
+	}
+
+	s.idCounter++
+	ch := &slack.Conversation{
+		ID:        "C" + strconv.Itoa(s.idCounter),
+		Name:      name,
+		NameNorm:  name,
+		Created:   time.Now().Unix(),
+		Creator:   s.botUID,
+		IsMember:  true,
+		IsPrivate: r.FormValue("is_private") == "true",
+	}
+	s.channels[ch.ID] = ch
+	s.members[ch.ID] = []string{s.botUID}
+	writeOK(w, map[string]interface{}{"channel": ch})
+}
+
+func (s *Server) handleConversationsArchive(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.channels[r.FormValue("channel")]
+	if !ok {
+		writeError(w, "channel_not_found")
+		return
+	}
+	if ch.IsArchived {
+		writeError(w, "already_archived")
+		return
+	}
+	ch.IsArchived = true
+	writeOK(w, map[string]interface{}{})
+}
+
+func (s *Server) handleConversationsInfo(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.channels[r.FormValue("channel")]
+	if !ok {
+		writeError(w, "channel_not_found")
+		return
+	}
+	writeOK(w, map[string]interface{}{"channel": ch})
+}
+
+func (s *Server) handleConversationsList(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+	excludeArchived := r.FormValue("exclude_archived") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := []slack.Conversation{}
+	for _, ch := range s.channels {
+		if ch.IsArchived && excludeArchived {
+			continue
+		}
+		channels = append(channels, *ch)
+	}
+	writeOK(w, map[string]interface{}{"channels": channels})
+}
+
+func (s *Server) handleConversationsMembers(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.members[r.FormValue("channel")]
+	if !ok {
+		writeError(w, "channel_not_found")
+		return
+	}
+	writeOK(w, map[string]interface{}{"members": members})
+}
+
+func (s *Server) handleConversationsInvite(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Conversation string `json:"channel"`
+		Users        string `json:"users"`
+	}
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "invalid_json")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.members[request.Conversation]
+	if !ok {
+		writeError(w, "channel_not_found")
+		return
+	}
+
+	for _, id := range strings.Split(request.Users, ",") {
+		if id == s.botUID {
+			writeError(w, "cant_invite_self")
+			return
+		}
+		if _, ok := s.profiles[id]; !ok {
+			writeError(w, "user_not_found")
+			return
+		}
+		for _, m := range members {
+			if m == id {
+				writeError(w, "already_in_channel")
+				return
+			}
+		}
+		members = append(members, id)
+	}
+	s.members[request.Conversation] = members
+	writeOK(w, map[string]interface{}{"channel": s.channels[request.Conversation]})
+}
+
+func (s *Server) handleIMList(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ims := []slack.IM{}
+	for userID, imID := range s.ims {
+		ims = append(ims, slack.IM{ID: imID, IsIM: true, User: userID})
+	}
+	writeOK(w, map[string]interface{}{"ims": ims})
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.FormValue("user")
+	p, ok := s.profiles[id]
+	if !ok {
+		writeError(w, "user_not_found")
+		return
+	}
+	writeOK(w, map[string]interface{}{"user": slack.User{ID: id, Name: p.DisplayName, RealName: p.RealName, TZ: s.tzs[id]}})
+}
+
+func (s *Server) handleUserProfile(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.profiles[r.FormValue("user")]
+	if !ok {
+		writeError(w, "user_not_found")
+		return
+	}
+	p.ID = "" // real API doesn't return it
+	writeOK(w, map[string]interface{}{"profile": p})
+}
+
+func (s *Server) handleTeamProfile(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := append([]slack.TeamProfileField{}, s.fields...)
+	writeOK(w, map[string]interface{}{"profile": map[string]interface{}{"fields": fields}})
+}
+
+// handleAuthTest reports the bot as the owner of any token, as the simulated channels are created by the bot
+func (s *Server) handleAuthTest(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	writeOK(w, map[string]interface{}{"user_id": s.botUID})
+}
+
+func (s *Server) handleRTMStart(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, "not_authed")
+		return
+	}
+
+	wsURL := "ws://" + r.Host + "/ws"
+	writeOK(w, map[string]interface{}{
+		"url":  wsURL,
+		"self": map[string]string{"id": s.botUID},
+	})
+}
+
+func (s *Server) handleWS(conn *ws.Conn) {
+	s.mu.Lock()
+	s.wsConns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.wsConns, conn)
+		s.mu.Unlock()
+		conn.Close() // not interested in this error, so skipping
+	}()
+
+	for {
+		var m slack.Message
+		if err := ws.JSON.Receive(conn, &m); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.rtmMessages = append(s.rtmMessages, m)
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) isIM(chanID string) bool {
+	for _, im := range s.ims {
+		if im == chanID {
+			return true
+		}
+	}
+	return false
+}
+
+func authorized(r *http.Request) bool {
+	return r.FormValue("token") != "" || strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func writeOK(w http.ResponseWriter, fields map[string]interface{}) {
+	fields["ok"] = true
+	writeJSON(w, fields)
+}
+
+func writeError(w http.ResponseWriter, msg string) {
+	writeJSON(w, map[string]interface{}{"ok": false, "error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Errorln("Unable to write response")
+	}
+}
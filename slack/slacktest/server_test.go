@@ -0,0 +1,125 @@
+package slacktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func newTestServer(t *testing.T) (*Server, *slack.HTTPClient) {
+	s := NewServerFromWorkspace(Workspace{
+		BotUID: "UBOT",
+		Profiles: []slack.UserProfile{
+			{ID: "U1", RealName: "Ann Lee", Skype: "24.03"},
+			{ID: "U2", RealName: "Bob Ray"},
+		},
+		Channels: []slack.Conversation{{ID: "CMAIN", Name: "general"}},
+		Members:  map[string][]string{"CMAIN": {"UBOT", "U1", "U2"}},
+		TZs:      map[string]string{"U1": "Europe/Moscow"},
+	})
+	s.Start()
+	t.Cleanup(s.Close)
+	return s, slack.NewHTTPClient(s.URL(), "bot-token", "legacy-token")
+}
+
+func TestServerConversations(t *testing.T) {
+	s, c := newTestServer(t)
+
+	chanID, err := c.CreateNewConversation("lee-bd-2019", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = c.CreateNewConversation("lee-bd-2019", true); err == nil || err.Error() != "API error: name_taken" {
+		t.Fatalf("expected name_taken error, got %v", err)
+	}
+
+	if err = c.InviteMembersToConversation(chanID, []string{"U2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if members := s.Members(chanID); len(members) != 2 || members[1] != "U2" {
+		t.Fatalf("expected bot and U2, got %v", members)
+	}
+
+	if err = c.SendAPIMessage(chanID, "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msgs := s.Messages(); len(msgs) != 1 || msgs[0].Conversation != chanID || msgs[0].Text != "hi" {
+		t.Fatalf("expected posted message, got %+v", msgs)
+	}
+
+	if err = c.ArchiveConversation(chanID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.ArchiveConversation(chanID); err == nil || err.Error() != "API error: already_archived" {
+		t.Fatalf("expected already_archived error, got %v", err)
+	}
+	active, err := c.GetConversations(false)
+	if err != nil || len(active) != 1 || active[0].ID != "CMAIN" {
+		t.Fatalf("expected only the main channel to be active, got %+v, %v", active, err)
+	}
+}
+
+func TestServerUsers(t *testing.T) {
+	_, c := newTestServer(t)
+
+	p, err := c.GetUserProfile("U1")
+	if err != nil || p.RealName != "Ann Lee" || p.Skype != "24.03" {
+		t.Fatalf("unexpected profile %+v, %v", p, err)
+	}
+	u, err := c.GetUserInfo("U1")
+	if err != nil || u.TZ != "Europe/Moscow" {
+		t.Fatalf("unexpected user %+v, %v", u, err)
+	}
+	if _, err = c.GetUserProfile("UNONE"); err == nil {
+		t.Fatal("expected error for unknown user")
+	}
+	if id, err := c.AuthTest(); err != nil || id != "UBOT" {
+		t.Fatalf("expected token of UBOT, got %q, %v", id, err)
+	}
+
+	im, err := c.FindDMByUserID("U2")
+	if err != nil || im == "" {
+		t.Fatalf("expected IM of U2, got %q, %v", im, err)
+	}
+	if err = c.SendAPIMessage(im, "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServerRTM(t *testing.T) {
+	s, c := newTestServer(t)
+
+	botUID, err := c.InitRTM()
+	if err != nil || botUID != "UBOT" {
+		t.Fatalf("unexpected bot ID %q, %v", botUID, err)
+	}
+	rtm, err := c.DialRTM()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rtm.Close()
+
+	// wait for the server to register the connection
+	deadline := time.Now().Add(2 * time.Second)
+	var m slack.Message
+	for time.Now().Before(deadline) {
+		s.SendRTMMessage(slack.Message{User: "U1", Conversation: "CMAIN", Text: "<@UBOT> hi"})
+		if m, err = rtm.GetMessage(); err == nil {
+			break
+		}
+	}
+	if m.Text != "<@UBOT> hi" || m.Type != slack.TypeMessage {
+		t.Fatalf("unexpected message %+v, %v", m, err)
+	}
+
+	if err = rtm.SendMessage(slack.Message{Type: slack.TypeMessage, Conversation: "CMAIN", Text: "hello"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for time.Now().Before(deadline) && len(s.RTMMessages()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if msgs := s.RTMMessages(); len(msgs) != 1 || msgs[0].Text != "hello" {
+		t.Fatalf("expected message sent via RTM, got %+v", msgs)
+	}
+}
@@ -1,5 +1,10 @@
 package slack
 
+import (
+	"bytes"
+	"encoding/json"
+)
+
 // Message describes Slack messages
 type Message struct {
 	ID           uint64 `json:"id"`
@@ -11,18 +16,66 @@ type Message struct {
 
 // UserProfile describes Slack user profile
 type UserProfile struct {
-	ID              string `json:"id,omitempty"`
-	Title           string `json:"title"`
-	Phone           string `json:"phone"`
-	Skype           string `json:"skype"` // used for birthday dates
-	RealName        string `json:"real_name"`
-	RealNameNorm    string `json:"real_name_norm"`
-	DisplayName     string `json:"display_name"`
-	DisplayNameNorm string `json:"display_name_norm"`
-	Email           string `json:"email"`
-	FirstName       string `json:"first_name"`
-	LastName        string `json:"last_name"`
-	ImageOriginal   string `json:"image_original"`
+	ID              string        `json:"id,omitempty"`
+	Title           string        `json:"title"`
+	Phone           string        `json:"phone"`
+	Skype           string        `json:"skype"` // used for birthday dates, if custom field is not set
+	RealName        string        `json:"real_name"`
+	RealNameNorm    string        `json:"real_name_norm"`
+	DisplayName     string        `json:"display_name"`
+	DisplayNameNorm string        `json:"display_name_norm"`
+	Email           string        `json:"email"`
+	FirstName       string        `json:"first_name"`
+	LastName        string        `json:"last_name"`
+	ImageOriginal   string        `json:"image_original"`
+	Fields          ProfileFields `json:"fields"`
+	// skipping all other fields intentionally
+}
+
+// User describes Slack user
+type User struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Deleted  bool   `json:"deleted"`
+	RealName string `json:"real_name"`
+	TZ       string `json:"tz"` // IANA time zone name, like Europe/Moscow
+	TZLabel  string `json:"tz_label"`
+	TZOffset int    `json:"tz_offset"` // in seconds
+	IsBot    bool   `json:"is_bot"`
+	// skipping all other fields intentionally
+}
+
+// ProfileFields describes values of the custom profile fields, keyed by field ID
+type ProfileFields map[string]ProfileFieldValue
+
+// UnmarshalJSON handles the empty array, which Slack returns instead of an empty object
+func (f *ProfileFields) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		*f = nil
+		return nil
+	}
+
+	var fields map[string]ProfileFieldValue
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*f = fields
+	return nil
+}
+
+// ProfileFieldValue describes the value of the custom profile field
+type ProfileFieldValue struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+// TeamProfileField describes the custom profile field of the workspace
+type TeamProfileField struct {
+	ID       string `json:"id"`
+	Ordering int    `json:"ordering"`
+	Label    string `json:"label"`
+	Hint     string `json:"hint"`
+	Type     string `json:"type"`
 	// skipping all other fields intentionally
 }
 
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// birthday source names, used in config
+const (
+	sourceSelf    = "self"
+	sourceProfile = "profile"
+	sourceRoster  = "roster"
+	sourceHR      = "hr"
+)
+
+// BirthdaySource provides users' birthdays in the DDMM format
+type BirthdaySource interface {
+	Name() string
+	Birthday(p *slack.UserProfile) (bd string, ok bool)
+}
+
+// selfSource reads birthdays set by users themselves via chat commands
+type selfSource struct {
+	db *DB
+}
+
+func (s *selfSource) Name() string {
+	return sourceSelf
+}
+
+func (s *selfSource) Birthday(p *slack.UserProfile) (string, bool) {
+	bd, err := s.db.GetUserBD(p.ID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get birthday of user %s", p.ID)
+		return "", false
+	}
+	if bd == nil {
+		return "", false
+	}
+	return bd.Birthday, true
+}
+
+// profileSource reads birthdays from the Slack profile
+type profileSource struct {
+	c *config
+}
+
+func (s *profileSource) Name() string {
+	return sourceProfile
+}
+
+func (s *profileSource) Birthday(p *slack.UserProfile) (string, bool) {
+	bd := profileBirthday(p, s.c)
+	return bd, bd != ""
+}
+
+// rosterSource reads birthdays from the local roster file, mapping Slack user IDs to dates
+type rosterSource struct {
+	birthdays map[string]string // user ID -> DDMM
+}
+
+func (s *rosterSource) Name() string {
+	return sourceRoster
+}
+
+func (s *rosterSource) Birthday(p *slack.UserProfile) (string, bool) {
+	bd, ok := s.birthdays[p.ID]
+	return bd, ok
+}
+
+// hrSource reads birthdays from the JSON file exported from the HR system.
+// Employees are matched either by Slack user ID or by email.
+type hrSource struct {
+	byID    map[string]string // user ID -> DDMM
+	byEmail map[string]string // lowercase email -> DDMM
+}
+
+// hrRecord describes a single employee in HR export
+type hrRecord struct {
+	SlackID   string `json:"slack_id"`
+	Email     string `json:"email"`
+	BirthDate string `json:"birth_date"`
+}
+
+func (s *hrSource) Name() string {
+	return sourceHR
+}
+
+func (s *hrSource) Birthday(p *slack.UserProfile) (string, bool) {
+	if bd, ok := s.byID[p.ID]; ok {
+		return bd, true
+	}
+	bd, ok := s.byEmail[strings.ToLower(p.Email)]
+	return bd, ok
+}
+
+// initBirthdaySources creates the birthday sources in the configured precedence order.
+// Birthdays set by users themselves always take precedence.
+func initBirthdaySources(c *config, db *DB, now time.Time) error {
+	names := c.BDSourceNames
+	if len(names) == 0 {
+		names = []string{sourceProfile}
+	}
+
+	c.BDSources = []BirthdaySource{&selfSource{db}}
+	for _, name := range names {
+		var (
+			src BirthdaySource
+			err error
+		)
+		switch strings.ToLower(name) {
+		case sourceProfile:
+			src = &profileSource{c}
+		case sourceRoster:
+			src, err = loadRoster(c.RosterFile, c.DateOrder, now)
+		case sourceHR:
+			src, err = loadHRExport(c.HRFile, c.DateOrder, now)
+		default:
+			err = errors.Errorf("unknown birthday source %q", name)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to init birthday source %s", name)
+		}
+		c.BDSources = append(c.BDSources, src)
+	}
+
+	return nil
+}
+
+// userBirthday returns the user's birthday from the first source which has it
+func userBirthday(p *slack.UserProfile, c *config) string {
+	var (
+		result    string
+		resultSrc string
+	)
+	for _, src := range c.BDSources {
+		bd, ok := src.Birthday(p)
+		if !ok {
+			continue
+		}
+		if result == "" {
+			result, resultSrc = bd, src.Name()
+			continue
+		}
+		if bd != result {
+			logrus.Debugf("Birthday sources disagree for user %s: %s has %s, %s has %s, using the first one",
+				p.ID, resultSrc, result, src.Name(), bd)
+		}
+	}
+	return result
+}
+
+func loadRoster(path string, order dateOrder, now time.Time) (*rosterSource, error) {
+	if path == "" {
+		return nil, errors.New("roster file is not set")
+	}
+
+	rows, err := readRoster(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &rosterSource{birthdays: make(map[string]string, len(rows))}
+	for _, row := range rows {
+		bd, err := normalizeFileBD(row[1], order, now)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping roster record for user %s", row[0])
+			continue
+		}
+		s.birthdays[row[0]] = bd
+	}
+	logrus.Infof("Loaded %d birthdays from roster %s", len(s.birthdays), path)
+	return s, nil
+}
+
+// readRoster reads 'user_id, date' rows from either CSV or YAML file
+func readRoster(path string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readRosterCSV(path)
+	case ".yaml", ".yml":
+		return readRosterYAML(path)
+	default:
+		return nil, errors.Errorf("unsupported roster file format %q", filepath.Ext(path))
+	}
+}
+
+// readRosterCSV reads 'user_id,birthday' rows, header is optional
+func readRosterCSV(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open roster file")
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read roster file")
+	}
+
+	var rows [][]string
+	for i, rec := range records {
+		if i == 0 && strings.EqualFold(rec[0], "user_id") {
+			continue
+		}
+		rows = append(rows, []string{strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])})
+	}
+	return rows, nil
+}
+
+// readRosterYAML reads 'user_id: birthday' mapping
+func readRosterYAML(path string) ([][]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read roster file")
+	}
+
+	var records map[string]string
+	if err = yaml.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrap(err, "unable to parse roster file")
+	}
+
+	rows := make([][]string, 0, len(records))
+	for id, bd := range records {
+		rows = append(rows, []string{strings.TrimSpace(id), strings.TrimSpace(bd)})
+	}
+	return rows, nil
+}
+
+func loadHRExport(path string, order dateOrder, now time.Time) (*hrSource, error) {
+	if path == "" {
+		return nil, errors.New("HR export file is not set")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read HR export file")
+	}
+
+	var records []hrRecord
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrap(err, "unable to parse HR export file")
+	}
+
+	s := &hrSource{byID: make(map[string]string), byEmail: make(map[string]string)}
+	for _, r := range records {
+		bd, err := normalizeFileBD(r.BirthDate, order, now)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping HR record for %s%s", r.SlackID, r.Email)
+			continue
+		}
+		if r.SlackID != "" {
+			s.byID[r.SlackID] = bd
+		}
+		if r.Email != "" {
+			s.byEmail[strings.ToLower(r.Email)] = bd
+		}
+	}
+	logrus.Infof("Loaded %d birthdays from HR export %s", len(records), path)
+	return s, nil
+}
+
+// normalizeFileBD converts the file birthday into the DDMM format
+func normalizeFileBD(bd string, order dateOrder, now time.Time) (string, error) {
+	d, err := parseBirthday(bd, order, now)
+	if err != nil {
+		return "", err
+	}
+	return d.DDMM(), nil
+}
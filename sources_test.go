@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+// writeTestFile writes the file into the test's temp dir and returns its path
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRoster(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		order   dateOrder
+		want    map[string]string
+		wantErr bool
+	}{
+		{"CSV with header", "roster.csv", "user_id,birthday\nU1,24.03\nU2, 1990-12-05\n", dayFirst,
+			map[string]string{"U1": "2403", "U2": "0512"}, false},
+		{"CSV without header", "roster.csv", "U1,03/04\nU2,not a date\n", dayFirst,
+			map[string]string{"U1": "0304"}, false},
+		{"CSV in mdy order", "roster.csv", "U1,03/04\n", monthFirst,
+			map[string]string{"U1": "0403"}, false},
+		{"YAML", "roster.yaml", "U1: 24 March\nU2: \"29.02\"\nU3: 31.04\n", dayFirst,
+			map[string]string{"U1": "2403", "U2": "2902"}, false},
+		{"broken CSV", "roster.csv", "U1,24.03,extra\n", dayFirst, nil, true},
+		{"unknown format", "roster.txt", "U1 24.03\n", dayFirst, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := loadRoster(writeTestFile(t, tt.file, tt.content), tt.order, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && !reflect.DeepEqual(src.birthdays, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, src.birthdays)
+			}
+		})
+	}
+}
+
+func TestLoadHRExport(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	path := writeTestFile(t, "hr.json", `[
+		{"slack_id": "U1", "birth_date": "1990-03-24"},
+		{"email": "Bob.Ray@Example.com", "birth_date": "1985-12-05"},
+		{"slack_id": "U3", "email": "cid@example.com", "birth_date": "2020-01-01"}
+	]`)
+	src, err := loadHRExport(path, dayFirst, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile slack.UserProfile
+		want    string
+		wantOK  bool
+	}{
+		{slack.UserProfile{ID: "U1", Email: "ann@example.com"}, "2403", true},
+		{slack.UserProfile{ID: "U2", Email: "bob.ray@example.com"}, "0512", true},
+		{slack.UserProfile{ID: "U3", Email: "cid@example.com"}, "", false}, // born in the future
+		{slack.UserProfile{ID: "U4"}, "", false},
+	}
+	for _, tt := range tests {
+		bd, ok := src.Birthday(&tt.profile)
+		if bd != tt.want || ok != tt.wantOK {
+			t.Errorf("user %s: expected %q (%t), got %q (%t)", tt.profile.ID, tt.want, tt.wantOK, bd, ok)
+		}
+	}
+}
+
+func TestUserBirthdayPrecedence(t *testing.T) {
+	_, db, c, _ := newTestBot(t)
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	c.RosterFile = writeTestFile(t, "roster.csv", "U1,01.01\nU2,02.02\n")
+	c.HRFile = writeTestFile(t, "hr.json", `[{"slack_id": "U1", "birth_date": "1990-03-03"}, {"slack_id": "U3", "birth_date": "1990-04-04"}]`)
+	if err := db.SaveUserBD("U2", userBD{Birthday: "0505"}); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := []*slack.UserProfile{
+		{ID: "U1", Skype: "06.06"},
+		{ID: "U2", Skype: "07.07"},
+		{ID: "U3", Skype: "08.08"},
+		{ID: "U4", Skype: "09.09"},
+		{ID: "U5"},
+	}
+	tests := []struct {
+		sources []string
+		want    []string // birthdays of the profiles
+	}{
+		{nil, []string{"06.06", "0505", "08.08", "09.09", ""}},
+		{[]string{"roster", "hr", "profile"}, []string{"0101", "0505", "0404", "09.09", ""}},
+		{[]string{"hr", "roster"}, []string{"0303", "0505", "0404", "", ""}},
+		{[]string{"profile", "hr"}, []string{"06.06", "0505", "08.08", "09.09", ""}},
+	}
+	for _, tt := range tests {
+		c.BDSourceNames = tt.sources
+		if err := initBirthdaySources(c, db, now); err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range profiles {
+			if got := userBirthday(p, c); got != tt.want[i] {
+				t.Errorf("sources %v, user %s: expected %q, got %q", tt.sources, p.ID, tt.want[i], got)
+			}
+		}
+	}
+
+	c.BDSourceNames = []string{"ldap"}
+	if err := initBirthdaySources(c, db, now); err == nil {
+		t.Error("expected an error for the unknown source")
+	}
+}
@@ -8,12 +8,57 @@ type config struct {
 
 	Location *time.Location
 
+	ManagerSchedule *schedule
+	ChannelSchedule *schedule
+
 	BotUID        string
+	APIURL        string
 	LegacyToken   string
 	MainChannelID string
 	ManagerID     string
 	ManagerDM     string
 
+	BDField   string // custom profile field label or ID
+	BDFieldID string
+	BDSources []BirthdaySource // in precedence order
+
+	DateOrder     dateOrder
+	LeapDay       leapDayPolicy
+	BDSourceNames []string
+	RosterFile    string
+	HRFile        string
+
+	Calendar           *workCalendar
+	ShiftAnnouncements bool // pull the announcements forward if the birthday is a day off
+	WorkdayTresholds   bool // count the tresholds in working days
+
+	Anniversaries     bool
+	AnnivSourceNames  []string
+	AnnivField        string // custom profile field label or ID
+	AnnivFieldID      string
+	AnnivRosterFile   string
+	AnnivRoster       map[string]string // user ID -> YYYY-MM-DD
+	AnnivHighTreshold int
+	AnnivLowTreshold  int
+
+	EventHighTreshold int
+	EventLowTreshold  int
+	EventsFile        string
+
+	MonthlyDigest   bool
+	DigestChannelID string
+	ManagerMode     string // default manager announcement mode: individual, daily or weekly digest
+
+	ReminderDays     []int // days before the celebration when the contributors are reminded
+	IdeasSummaryDays int   // days before the celebration when the gift ideas are summarized
+
+	ArchiveChannels  bool
+	ArchiveGraceDays int // days after the celebration before the channel is archived
+
+	CongratsHour    int // local hour of the birthday congratulation
+	CongratsDM      bool
+	CongratsChannel bool
+
 	BDHighTreshold int
 	BDLowTreshold  int
 
@@ -29,11 +74,113 @@ type messages struct {
 	PersonalToday    string
 	ManagerAnnounce  string
 	ChannelAnnounce  string
+	PersonalSaved    string
+	PersonalForgot   string
+	NotAllowed       string
+	BelatedAnnounce  string
+	CongratsDM       string
+	CongratsChannel  string
+
+	AnnivManagerAnnounce string
+	AnnivChannelAnnounce string
+	AnnivSaved           string
+
+	EventManagerAnnounce string
+	EventChannelAnnounce string
+	EventSaved           string
+	EventRemoved         string
+	EventNotFound        string
+	EventAddError        string
+	EventUsage           string
+	EventsEmpty          string
+
+	UpcomingHeader string
+	UpcomingEmpty  string
+	UpcomingLine   string
+	UpcomingToday  string
+	UpcomingUsage  string
+	MonthlyDigest  string
+
+	ManagerDigest          string
+	ManagerDigestMissing   string
+	ManagerDigestNoChannel string
+	DigestSaved            string
+	DigestUnknownMode      string
+
+	CollectionPaid     string
+	CollectionStatus   string
+	CollectionClosed   string
+	CollectionNotFound string
+	CollectionSkipped  string
+	CollectionReminder string
+	CollectionUsage    string
+	AmountError        string
+
+	IdeaSaved    string
+	IdeasList    string
+	IdeasEmpty   string
+	IdeasSummary string
+	VoteSaved    string
+	IdeaUsage    string
+	VoteUsage    string
+
+	WishSaved      string
+	WishRemoved    string
+	WishlistList   string
+	WishlistEmpty  string
+	WishlistShared string
+	WishDMOnly     string
+	WishUsage      string
+	WishNotFound   string
+
+	ChannelClosing string
 }
 
 type bdInfo struct {
 	RealName string
 	Surname  string
-	Birthday string
+	Birthday string // DDMMYYYY, where YYYY is the year of the celebration
 	DaysLeft int
+	Date     time.Time // date of the celebration
+	Kind     eventKind
+	Years    int      // years with us, for anniversaries
+	Honorees []string // user IDs, for custom events
+}
+
+// eventKind describes the celebrated event
+type eventKind int
+
+// event kinds
+const (
+	eventBirthday eventKind = iota
+	eventAnniversary
+	eventCustom
+)
+
+// announceKind describes which announcements should be sent during the check
+type announceKind int
+
+// announcement kinds
+const (
+	announceManager announceKind = 1 << iota
+	announceChannels
+)
+
+type announcePlan struct {
+	Manager map[string]bdInfo // birthdays to announce to the manager
+	Channel map[string]bdInfo // birthdays to create the channels for
+	Belated map[string]bdInfo // birthdays which passed while the bot was down
+
+	AnnivManager map[string]bdInfo // work anniversaries to announce to the manager
+	AnnivChannel map[string]bdInfo // work anniversaries to create the channels for
+
+	EventManager map[string]bdInfo // custom events to announce to the manager, by event ID
+	EventChannel map[string]bdInfo // custom events to create the channels for, by event ID
+}
+
+// userBD describes the birthday set by the user via chat command
+type userBD struct {
+	Birthday string `json:"birthday"` // DDMM
+	Year     int    `json:"year,omitempty"`
+	Private  bool   `json:"private,omitempty"`
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandUpcoming = "upcoming"
+
+	defaultUpcomingDays = 30
+	maxUpcomingDays     = 366
+	upcomingPageSize    = 20 // lines per message
+
+	profilesCacheTTL = 10 * time.Minute
+)
+
+var upcomingArgsRe = regexp.MustCompile(`^(\d+)\s*(?:d|days?)?$`)
+
+// profilesCache keeps the main channel profiles for the chat commands, so that Slack is not asked on every request
+type profilesCache struct {
+	mu       sync.Mutex
+	profiles []*slack.UserProfile
+	expires  time.Time
+}
+
+var commandProfiles profilesCache
+
+// get returns the cached profiles or gathers them again, if they're expired
+func (pc *profilesCache) get(sc slack.Client, db *DB, c *config, now time.Time) ([]*slack.UserProfile, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.profiles != nil && now.Before(pc.expires) {
+		return pc.profiles, nil
+	}
+
+	profiles, err := mainChannelProfiles(sc, db, c)
+	if err != nil {
+		return nil, err
+	}
+	pc.profiles, pc.expires = profiles, now.Add(profilesCacheTTL)
+	return profiles, nil
+}
+
+// reset drops the cached profiles, so that the next request gathers them again
+func (pc *profilesCache) reset() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.profiles = nil
+}
+
+// upcomingBD describes the birthday in the upcoming list
+type upcomingBD struct {
+	UserID   string
+	RealName string
+	DaysLeft int
+	Date     time.Time
+	Channel  string // birthday channel name, if it was created
+}
+
+// parseUpcomingArgs parses the amount of days, like '14', '14d' or '14 days'
+func parseUpcomingArgs(args string) (int, error) {
+	args = strings.ToLower(strings.TrimSpace(args))
+	if args == "" {
+		return defaultUpcomingDays, nil
+	}
+
+	parts := upcomingArgsRe.FindStringSubmatch(args)
+	if parts == nil {
+		return 0, errors.Errorf("%q is not an amount of days", args)
+	}
+	days, _ := strconv.Atoi(parts[1])
+	if days < 1 || days > maxUpcomingDays {
+		return 0, errors.Errorf("amount of days should be between 1 and %d", maxUpcomingDays)
+	}
+	return days, nil
+}
+
+// handleUpcoming lists the birthdays in the next days, private ones are shown only to their owners.
+// Birthday channels are secret for the honorees, so they're listed only in DM.
+func handleUpcoming(rtm slack.RTM, sc slack.Client, clk clock, db *DB, c *config, msgs *messages, m slack.Message, args string) {
+	days, err := parseUpcomingArgs(args)
+	if err != nil {
+		logrus.WithError(err).Infof("Unable to parse upcoming days of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.UpcomingUsage, m.User, maxUpcomingDays))
+		return
+	}
+
+	now := clk.Now().In(c.Location)
+	profiles, err := commandProfiles.get(sc, db, c, now)
+	if err != nil {
+		logrus.WithError(err).Errorln("Unable to get main channel profiles")
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+
+	list := upcomingBirthdays(now, profiles, db, c, days, m.User)
+	if len(list) == 0 {
+		sendReply(rtm, m, fmt.Sprintf(msgs.UpcomingEmpty, days))
+		return
+	}
+	if !isDirectMessage(m) {
+		for i := range list {
+			list[i].Channel = ""
+		}
+	}
+
+	// split the list into pages, so that the messages are not too long
+	for i := 0; i < len(list); i += upcomingPageSize {
+		end := i + upcomingPageSize
+		if end > len(list) {
+			end = len(list)
+		}
+
+		lines := make([]string, 0, end-i+1)
+		if i == 0 {
+			lines = append(lines, fmt.Sprintf(msgs.UpcomingHeader, days))
+		}
+		for _, bd := range list[i:end] {
+			lines = append(lines, upcomingLine(msgs, bd))
+		}
+		sendReply(rtm, m, strings.Join(lines, "\n"))
+	}
+}
+
+// upcomingBirthdays returns the birthdays in the next days sorted by date.
+// Private birthdays and the channel of the requesting user's own birthday are hidden.
+func upcomingBirthdays(now time.Time, profiles []*slack.UserProfile, db *DB, c *config, days int, requester string) []upcomingBD {
+	var list []upcomingBD
+	for _, p := range profiles {
+		bd, err := parseBirthday(userBirthday(p, c), c.DateOrder, now)
+		if err != nil {
+			continue
+		}
+
+		left, date, err := getUserBDInfo(now, bd.DDMM(), c.LeapDay)
+		if err != nil || left > days {
+			continue
+		}
+
+		if p.ID != requester && isBDPrivate(db, p.ID) {
+			continue
+		}
+
+		item := upcomingBD{p.ID, p.RealName, left, date, ""}
+		if p.ID != requester {
+			info := bdInfo{p.RealName, strings.ToLower(p.LastName), bd.DDMM() + strconv.Itoa(date.Year()), left, date, eventBirthday, 0, nil}
+			if ok, _ := db.CheckUserBDInCache(db.ChannelBucketName, p.ID, info.Birthday); ok {
+				item.Channel = bdChannelName(info)
+			}
+		}
+		list = append(list, item)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].DaysLeft != list[j].DaysLeft {
+			return list[i].DaysLeft < list[j].DaysLeft
+		}
+		return list[i].RealName < list[j].RealName
+	})
+	return list
+}
+
+func upcomingLine(msgs *messages, bd upcomingBD) string {
+	line := fmt.Sprintf(msgs.UpcomingLine, bd.Date.Format("02.01"), bd.RealName, bd.DaysLeft)
+	if bd.DaysLeft == 0 {
+		line = fmt.Sprintf(msgs.UpcomingToday, bd.Date.Format("02.01"), bd.RealName)
+	}
+	if bd.Channel != "" {
+		line += ", #" + bd.Channel
+	}
+	return line
+}
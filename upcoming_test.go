@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestHandleUpcoming(t *testing.T) {
+	sc, db, c, m := newTestBot(t)
+	m.UpcomingHeader, m.UpcomingEmpty = "Birthdays in the next %d days:", "No birthdays in the next %d days"
+	m.UpcomingLine, m.UpcomingToday = "• %s %s, in %d day(s)", "• %s %s, today"
+	m.UpcomingUsage = "<@%s>, try `upcoming 14 days`, up to %d days"
+
+	commandProfiles.reset()
+	now := time.Date(2019, 2, 26, 10, 0, 0, 0, time.UTC)
+	if err := db.SaveUserBDToCache(db.ChannelBucketName, "U2", "03032019"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		conversation string
+		args         string
+		want         []string
+		notWant      []string
+	}{
+		{"main channel", "CMAIN", "7", []string{"28.02 Ann Lee, in 2 day(s)", "03.03 Bob Ray, in 5 day(s)"}, []string{"#ray-bd-2019"}},
+		{"DM", "DU1", "7 days", []string{"03.03 Bob Ray, in 5 day(s), #ray-bd-2019"}, nil},
+		{"bad amount of days", "DU1", "lots", []string{"<@U1>, try `upcoming 14 days`, up to 366 days"}, []string{"Bob Ray"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rtm := slack.NewFakeRTM()
+			msg := slack.Message{Type: "message", User: "U1", Conversation: tt.conversation}
+			handleUpcoming(rtm, sc, fixedClock{now}, db, c, m, msg, tt.args)
+
+			var texts []string
+			for _, sent := range rtm.SentMessages() {
+				texts = append(texts, sent.Text)
+			}
+			reply := strings.Join(texts, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(reply, want) {
+					t.Errorf("expected %q in the reply:\n%s", want, reply)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(reply, notWant) {
+					t.Errorf("expected no %q in the reply:\n%s", notWant, reply)
+				}
+			}
+		})
+	}
+}
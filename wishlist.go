@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandWish = "wish"
+
+	wishActionAdd    = "add"
+	wishActionList   = "list"
+	wishActionRemove = "remove"
+)
+
+// wishlistText formats the wishes with their numbers
+func wishlistText(wishes []string) string {
+	lines := make([]string, 0, len(wishes))
+	for i, w := range wishes {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, w))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shareWishlist posts the honoree's wishlist to the birthday channel, if there is any
+func shareWishlist(sc slack.Client, db *DB, m *messages, chanID, userID string, info bdInfo) error {
+	wishes, err := db.GetWishlist(userID)
+	if err != nil {
+		return err
+	}
+	if len(wishes) == 0 {
+		return nil
+	}
+
+	if err = sc.SendAPIMessage(chanID, fmt.Sprintf(m.WishlistShared, info.RealName, wishlistText(wishes))); err != nil {
+		return errors.Wrapf(err, "unable to send wishlist to channel with ID %s", chanID)
+	}
+	logrus.Infof("Shared wishlist of user %s in channel %s", userID, chanID)
+	return nil
+}
+
+// handleWishCommand manages the user's own wishlist.
+// Wishlist is a secret for the people around, so the commands are accepted only in DM.
+func handleWishCommand(rtm slack.RTM, db *DB, msgs *messages, m slack.Message, args string) {
+	if !isDirectMessage(m) {
+		sendReply(rtm, m, fmt.Sprintf(msgs.WishDMOnly, m.User))
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		sendReply(rtm, m, fmt.Sprintf(msgs.WishUsage, m.User))
+		return
+	}
+
+	wishes, err := db.GetWishlist(m.User)
+	if err != nil {
+		logrus.WithError(err).Errorf("Unable to get wishlist of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+
+	var reply string
+	switch strings.ToLower(fields[0]) {
+	case wishActionAdd:
+		wish := strings.TrimSpace(args[len(fields[0]):])
+		if wish == "" {
+			sendReply(rtm, m, fmt.Sprintf(msgs.WishUsage, m.User))
+			return
+		}
+		wishes = append(wishes, wish)
+		reply = fmt.Sprintf(msgs.WishSaved, m.User, len(wishes))
+	case wishActionRemove:
+		n := 0
+		if len(fields) == 2 {
+			n, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+		}
+		if n < 1 || n > len(wishes) {
+			sendReply(rtm, m, fmt.Sprintf(msgs.WishNotFound, m.User, strings.Join(fields[1:], " ")))
+			return
+		}
+		wishes = append(wishes[:n-1], wishes[n:]...)
+		reply = fmt.Sprintf(msgs.WishRemoved, m.User, n)
+	case wishActionList:
+		if len(wishes) == 0 {
+			sendReply(rtm, m, fmt.Sprintf(msgs.WishlistEmpty, m.User))
+		} else {
+			sendReply(rtm, m, fmt.Sprintf(msgs.WishlistList, m.User, wishlistText(wishes)))
+		}
+		return
+	default:
+		sendReply(rtm, m, fmt.Sprintf(msgs.WishUsage, m.User))
+		return
+	}
+
+	if err = db.SaveWishlist(m.User, wishes); err != nil {
+		logrus.WithError(err).Errorf("Unable to save wishlist of user %s", m.User)
+		sendReply(rtm, m, fmt.Sprintf(msgs.ProfileError, m.User))
+		return
+	}
+	logrus.Infof("User %s has updated the wishlist", m.User)
+	sendReply(rtm, m, reply)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nezorflame/bd-reminder-bot/slack"
+)
+
+func TestHandleWishCommandOnlyInDM(t *testing.T) {
+	_, db, _, m := newTestBot(t)
+	m.WishSaved, m.WishlistList, m.WishDMOnly = "<@%s>, wish #%d", "<@%s>:\n%s", "<@%s>, DM only"
+
+	tests := []struct {
+		conversation, args string
+		want               string
+	}{
+		{"CMAIN", "add Coffee grinder", "<@U1>, DM only"},
+		{"DU1", "add Coffee grinder", "<@U1>, wish #1"},
+		{"CMAIN", "list", "<@U1>, DM only"},
+		{"DU1", "list", "<@U1>:\n1. Coffee grinder"},
+	}
+	for _, tt := range tests {
+		rtm := slack.NewFakeRTM()
+		handleWishCommand(rtm, db, m, slack.Message{Type: "message", User: "U1", Conversation: tt.conversation}, tt.args)
+
+		sent := rtm.SentMessages()
+		if len(sent) != 1 || sent[0].Text != tt.want {
+			t.Errorf("wish %s in %s: expected reply %q, got %+v", tt.args, tt.conversation, tt.want, sent)
+		}
+	}
+}
+
+func TestHandleWishCommandUsage(t *testing.T) {
+	_, db, _, m := newTestBot(t)
+	m.WishSaved, m.WishRemoved = "<@%s>, wish #%d", "<@%s>, removed #%d"
+	m.WishUsage, m.WishNotFound = "<@%s>, usage", "<@%s>, no wish #%s"
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "<@U1>, usage"},
+		{"add", "<@U1>, usage"},
+		{"buy Coffee grinder", "<@U1>, usage"},
+		{"add Coffee grinder", "<@U1>, wish #1"},
+		{"remove 2", "<@U1>, no wish #2"},
+		{"remove first", "<@U1>, no wish #first"},
+		{"remove #1", "<@U1>, removed #1"},
+	}
+	for _, tt := range tests {
+		rtm := slack.NewFakeRTM()
+		handleWishCommand(rtm, db, m, slack.Message{Type: "message", User: "U1", Conversation: "DU1"}, tt.args)
+
+		sent := rtm.SentMessages()
+		if len(sent) != 1 || sent[0].Text != tt.want {
+			t.Errorf("wish %q: expected reply %q, got %+v", tt.args, tt.want, sent)
+		}
+	}
+}